@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bigelle/ghostman/internal/httpcore"
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/spf13/cobra"
+)
+
+// PartSuffix names the file a body is written to before it's complete and
+// renamed into place. --continue resumes it.
+const PartSuffix = ".part"
+
+// BackupSuffix is added to a file --backup moves out of the way.
+const BackupSuffix = "~"
+
+// PrepareResume asks only for what's missing from a partial download of
+// the --out file when --continue is given, and returns the size already
+// there.
+func PrepareResume(cmd *cobra.Command, opts Options, req *httpcore.RequestConf) (int64, error) {
+	if !opts.Continue {
+		return 0, nil
+	}
+	if opts.Out == "" {
+		return 0, fmt.Errorf("--continue needs --out")
+	}
+	if c := req.Settings().Compressed; c != nil && *c {
+		return 0, fmt.Errorf("--continue can't be used with --compressed, ranges would count compressed bytes")
+	}
+
+	part := opts.Out + PartSuffix
+	info, err := os.Stat(part)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("checking %s: %w", part, err)
+	}
+	if info.Size() == 0 {
+		return 0, nil
+	}
+
+	state, err := httpcore.LoadResumeState(opts.Out)
+	if err != nil {
+		return 0, err
+	}
+
+	validator := ""
+	switch u := req.ToHTTP().URL.String(); {
+	case state == nil:
+		PrintWarnings(cmd, []string{fmt.Sprintf("no %s found, resuming %s without checking that the resource didn't change", opts.Out+httpcore.ResumeSuffix, part)})
+	case state.URL != u:
+		PrintWarnings(cmd, []string{fmt.Sprintf("%s was downloaded from %s, starting over", part, state.URL)})
+		return 0, nil
+	default:
+		validator = state.Validator()
+	}
+
+	req.SetRange(info.Size(), validator)
+	return info.Size(), nil
+}
+
+// OutPath is where the body goes: --out, or a name inside --out-dir taken
+// from Content-Disposition or else from the URL.
+func OutPath(opts Options, resp *httpcore.Response) string {
+	if opts.OutDir == "" {
+		return opts.Out
+	}
+
+	ct := resp.ContentType()
+
+	name := ""
+	if _, params, err := mime.ParseMediaType(resp.ToHTTP().Header.Get("Content-Disposition")); err == nil {
+		// filename* is decoded into filename
+		name = safeFileName(params["filename"])
+	}
+	if name == "" {
+		name = safeFileName(path.Base(resp.URL().Path))
+	}
+	if name == "" {
+		name = "index"
+	}
+
+	if filepath.Ext(name) == "" {
+		mediaType, _, _ := mime.ParseMediaType(ct)
+		if m := mimetype.Lookup(mediaType); m != nil {
+			name += m.Extension()
+		}
+	}
+
+	return filepath.Join(opts.OutDir, name)
+}
+
+// safeFileName keeps the last element of a name sent by the server, so it
+// can't point outside the output directory.
+func safeFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	switch name {
+	case ".", "..", "/":
+		return ""
+	}
+	return strings.TrimSpace(name)
+}
+
+// OpenOut opens the partial file of target for resp, appending if it
+// continues the offset bytes already there. it returns nil when there's
+// nothing to write, as for a download that was already complete.
+func OpenOut(cmd *cobra.Command, opts Options, target string, resp *httpcore.Response, offset int64) (*os.File, error) {
+	res := resp.ToHTTP()
+	part := target + PartSuffix
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+
+	if offset > 0 {
+		switch res.StatusCode {
+		case http.StatusPartialContent:
+			cr, err := httpcore.ParseContentRange(res.Header.Get("Content-Range"))
+			if err != nil {
+				return nil, err
+			}
+			if cr.First != offset {
+				return nil, fmt.Errorf("asked to resume at byte %d, the server sent bytes from %d", offset, cr.First)
+			}
+			flags = os.O_WRONLY | os.O_APPEND
+		case http.StatusRequestedRangeNotSatisfiable:
+			cr, err := httpcore.ParseContentRange(res.Header.Get("Content-Range"))
+			if err != nil || cr.Size != offset {
+				return nil, fmt.Errorf("can't resume %s: the server has no bytes after %d", part, offset)
+			}
+			PrintWarnings(cmd, []string{fmt.Sprintf("%s was already complete", part)})
+			return nil, commitOut(target, opts)
+		case http.StatusOK:
+			PrintWarnings(cmd, []string{"the server sent the whole body instead of the rest, downloading it again"})
+		default:
+			return nil, fmt.Errorf("the server answered %s, %s was left as it was", httpcore.Status(res.StatusCode), part)
+		}
+	}
+
+	// names picked from the response fit it by definition
+	if opts.Out != "" && !opts.ForceExt {
+		ext := filepath.Ext(target)
+		ct := resp.ContentType()
+
+		exts, err := mime.ExtensionsByType(ct)
+		if err != nil {
+			return nil, fmt.Errorf("checking if file extension is suitable for the response body: %w", err)
+		}
+
+		if !slices.Contains(exts, ext) {
+			return nil, fmt.Errorf("can't write %s into %s file, use --force-ext to write it anyway", ct, ext)
+		}
+	}
+
+	if opts.OutDir != "" {
+		if err := os.MkdirAll(opts.OutDir, 0o755); err != nil {
+			return nil, fmt.Errorf("creating %s: %w", opts.OutDir, err)
+		}
+	}
+
+	f, err := os.OpenFile(part, flags, 0o644)
+	if err != nil {
+		if os.IsPermission(err) {
+			return nil, fmt.Errorf("opening file for writing: access denied")
+		}
+		return nil, fmt.Errorf("opening file for writing: %w", err)
+	}
+
+	return f, nil
+}
+
+// checkDownload compares the partial file of target with the size the
+// server announced.
+func checkDownload(target string, state httpcore.ResumeState) error {
+	part := target + PartSuffix
+	info, err := os.Stat(part)
+	if err != nil {
+		return fmt.Errorf("checking %s: %w", part, err)
+	}
+
+	if state.Size >= 0 && info.Size() != state.Size {
+		return fmt.Errorf("incomplete download: %s holds %s of %s, run again with --continue to resume",
+			part, httpcore.FormatBytes(info.Size()), httpcore.FormatBytes(state.Size))
+	}
+	return nil
+}
+
+// commitOut renames the complete partial file over target, moving an
+// existing target to a backup first if asked to, and drops the resume
+// state. with --no-clobber the file is linked instead, which fails rather
+// than replace a target created while downloading.
+func commitOut(target string, opts Options) error {
+	part := target + PartSuffix
+
+	switch {
+	case opts.NoClobber:
+		if err := os.Link(part, target); err != nil {
+			if errors.Is(err, os.ErrExist) {
+				return fmt.Errorf("%s appeared while downloading, not overwriting it; the body is in %s", target, part)
+			}
+			return fmt.Errorf("saving %s: %w", target, err)
+		}
+		if err := os.Remove(part); err != nil {
+			return fmt.Errorf("removing %s: %w", part, err)
+		}
+	default:
+		if opts.Backup {
+			err := os.Rename(target, target+BackupSuffix)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("backing up %s: %w", target, err)
+			}
+		}
+		if err := os.Rename(part, target); err != nil {
+			return fmt.Errorf("saving %s: %w", target, err)
+		}
+	}
+
+	return httpcore.RemoveResumeState(target)
+}
+
+// exists tells whether --no-clobber has to leave path alone.
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
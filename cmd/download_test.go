@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bigelle/ghostman/internal/httpcore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSafeFileName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"report.pdf", "report.pdf"},
+		{" report.pdf ", "report.pdf"},
+		{"../../.bashrc", ".bashrc"},
+		{`..\x`, "x"},
+		{`C:\Users\me\x.txt`, "x.txt"},
+		{"/etc/passwd", "passwd"},
+		{"dir/", "dir"},
+		{"..", ""},
+		{".", ""},
+		{"/", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, safeFileName(tt.name))
+		})
+	}
+}
+
+func TestOutPath(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cd := r.URL.Query().Get("cd"); cd != "" {
+			w.Header().Set("Content-Disposition", cd)
+		}
+		w.Header().Set("Content-Type", r.URL.Query().Get("ct"))
+		w.Write([]byte("x"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		opts Options
+		path string
+		want string
+	}{
+		{
+			name: "out wins",
+			opts: Options{Out: "body.json"},
+			path: "/files/report.csv?ct=text/csv",
+			want: "body.json",
+		},
+		{
+			name: "from url",
+			opts: Options{OutDir: dir},
+			path: "/files/report.csv?ct=text/csv",
+			want: filepath.Join(dir, "report.csv"),
+		},
+		{
+			name: "content-disposition",
+			opts: Options{OutDir: dir},
+			path: "/download?ct=text/csv&cd=" + "attachment%3B%20filename=%22report.csv%22",
+			want: filepath.Join(dir, "report.csv"),
+		},
+		{
+			name: "content-disposition filename*",
+			opts: Options{OutDir: dir},
+			path: "/download?ct=application/pdf&cd=" + "attachment%3B%20filename*=UTF-8''r%25C3%25A9sum%25C3%25A9.pdf",
+			want: filepath.Join(dir, "résumé.pdf"),
+		},
+		{
+			name: "content-disposition traversal",
+			opts: Options{OutDir: dir},
+			path: "/download?ct=text/plain&cd=" + "attachment%3B%20filename=%22../../.bashrc%22",
+			want: filepath.Join(dir, ".bashrc"),
+		},
+		{
+			name: "malformed content-disposition",
+			opts: Options{OutDir: dir},
+			path: "/files/report.csv?ct=text/csv&cd=" + "attachment%3B%20filename=%22",
+			want: filepath.Join(dir, "report.csv"),
+		},
+		{
+			name: "extension from content type",
+			opts: Options{OutDir: dir},
+			path: "/api/users?ct=application/json",
+			want: filepath.Join(dir, "users.json"),
+		},
+		{
+			name: "index",
+			opts: Options{OutDir: dir},
+			path: "/?ct=text/html%3B%20charset=utf-8",
+			want: filepath.Join(dir, "index.html"),
+		},
+		{
+			name: "unknown content type",
+			opts: Options{OutDir: dir},
+			path: "/blob?ct=application/x-made-up",
+			want: filepath.Join(dir, "blob"),
+		},
+	}
+
+	client := httpcore.NewClient()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := httpcore.NewRequest(srv.URL + tt.path)
+			require.NoError(t, err)
+			resp, err := client.Send(context.Background(), req)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, OutPath(tt.opts, resp))
+		})
+	}
+}
+
+func TestCommitOut(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		existing bool
+		wantErr  bool
+		want     string
+		wantBak  bool
+		wantPart bool
+	}{
+		{name: "new", want: "new"},
+		{name: "overwrite", existing: true, want: "new"},
+		{name: "backup", opts: Options{Backup: true}, existing: true, want: "new", wantBak: true},
+		{name: "backup nothing", opts: Options{Backup: true}, want: "new"},
+		{name: "no clobber", opts: Options{NoClobber: true}, want: "new"},
+		{name: "no clobber existing", opts: Options{NoClobber: true}, existing: true, wantErr: true, want: "old", wantPart: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := filepath.Join(t.TempDir(), "out.txt")
+			require.NoError(t, os.WriteFile(target+PartSuffix, []byte("new"), 0o644))
+			require.NoError(t, os.WriteFile(target+httpcore.ResumeSuffix, []byte("{}"), 0o644))
+			if tt.existing {
+				require.NoError(t, os.WriteFile(target, []byte("old"), 0o644))
+			}
+
+			err := commitOut(target, tt.opts)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.NoFileExists(t, target+httpcore.ResumeSuffix)
+			}
+
+			b, err := os.ReadFile(target)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(b))
+
+			if tt.wantBak {
+				b, err := os.ReadFile(target + BackupSuffix)
+				require.NoError(t, err)
+				assert.Equal(t, "old", string(b))
+			} else {
+				assert.NoFileExists(t, target+BackupSuffix)
+			}
+
+			if tt.wantPart {
+				assert.FileExists(t, target+PartSuffix)
+			} else {
+				assert.NoFileExists(t, target+PartSuffix)
+			}
+		})
+	}
+}
@@ -2,15 +2,19 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
-	"mime"
+	"net/http"
 	"os"
 	"path/filepath"
-	"slices"
 	"strings"
 
+	"github.com/bigelle/ghostman/internal/collection"
+	"github.com/bigelle/ghostman/internal/curl"
+	"github.com/bigelle/ghostman/internal/har"
 	"github.com/bigelle/ghostman/internal/httpcore"
+	"github.com/bigelle/ghostman/internal/httpfile"
 	"github.com/bigelle/ghostman/internal/shared"
 	"github.com/gabriel-vasile/mimetype"
 	"github.com/spf13/cobra"
@@ -28,10 +32,44 @@ type Options struct {
 	Verbose     bool
 	Out         string
 	PrintOut    bool
+	As          string
+	Har         string
+	Jar         string
+	// JarOut is where the jar is exported as well, e.g. as cookies.txt
+	// for curl
+	JarOut  string
+	Timing  string
+	TLSInfo bool
+	// CertWarnDays warns about certificates expiring within that many
+	// days, 0 disables the check
+	CertWarnDays int
+	NoProgress   bool
+	// Continue resumes a partial Out file, Segments downloads it over that
+	// many connections
+	Continue bool
+	Segments int
+	// OutDir saves the body under the name the server suggests. ForceExt
+	// skips checking that the Out extension fits the content type
+	OutDir   string
+	ForceExt bool
+	// NoClobber leaves existing files alone, Backup keeps them with
+	// BackupSuffix
+	NoClobber bool
+	Backup    bool
 }
 
 func PreRunHttp(cmd *cobra.Command, args []string) (err error) {
-	reqURL, err := httpcore.DetectSchema(args[0])
+	settings, err := ParseSettingsFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	clientOpts, err := settings.ClientOptions()
+	if err != nil {
+		return fmt.Errorf("configuring client: %w", err)
+	}
+
+	reqURL, err := httpcore.NewClient(clientOpts...).DetectSchema(args[0])
 	if err != nil {
 		return fmt.Errorf("detecting URL schema: %w", err)
 	}
@@ -55,13 +93,7 @@ func PreRunHttp(cmd *cobra.Command, args []string) (err error) {
 		return fmt.Errorf("error in pre-run task for http: %w", err)
 	}
 
-	ctx := cmd.Context()
-	withVal := context.WithValue(ctx, ctxKeyHttpReq, req)
-
-	opts := GetOptions(cmd)
-	withVal = context.WithValue(withVal, ctxKeyHttpOpts, opts)
-
-	cmd.SetContext(withVal)
+	SetRequestContext(cmd, req)
 
 	return nil
 }
@@ -70,6 +102,60 @@ func RunHttp(cmd *cobra.Command, args []string) (err error) {
 	opts := cmd.Context().Value(ctxKeyHttpOpts).(Options)
 	req := cmd.Context().Value(ctxKeyHttpReq).(*httpcore.RequestConf)
 
+	if opts.JarOut != "" && opts.Jar == "" {
+		return fmt.Errorf("--jar-out needs --jar")
+	}
+
+	var jar *httpcore.CookieJar
+	if opts.Jar != "" {
+		jar, err = httpcore.LoadCookieJarFile(opts.Jar)
+		if err != nil {
+			return err
+		}
+		jar.Attach(req)
+	}
+
+	switch opts.As {
+	case "":
+	case "curl":
+		var c string
+		c, err = curl.Command(req)
+		if err != nil {
+			return fmt.Errorf("rendering curl command: %w", err)
+		}
+		fmt.Println(c)
+		return nil
+	default:
+		return fmt.Errorf("unknown format for --as: %s", opts.As)
+	}
+
+	switch opts.Timing {
+	case "", "tree", "json":
+	default:
+		return fmt.Errorf("unknown format for --timing: %s", opts.Timing)
+	}
+
+	if err = req.SetProto(req.Settings().ExpectedProto(req.ToHTTP().URL)); err != nil {
+		return err
+	}
+
+	if opts.Segments < 1 {
+		return fmt.Errorf("invalid --segments: must be at least 1")
+	}
+	if opts.Segments > 1 && (opts.Out == "" && opts.OutDir == "" || opts.PrintOut) {
+		return fmt.Errorf("--segments needs --out or --out-dir and can't be used with --print-out")
+	}
+	if opts.Out != "" && opts.OutDir != "" {
+		return fmt.Errorf("only one of --out and --out-dir can be used")
+	}
+	if opts.NoClobber && opts.Backup {
+		return fmt.Errorf("only one of --no-clobber and --backup can be used")
+	}
+	offset, err := PrepareResume(cmd, opts, req)
+	if err != nil {
+		return err
+	}
+
 	str, err := req.ToString()
 	if err != nil {
 		return fmt.Errorf("formatting request: %w", err)
@@ -77,14 +163,63 @@ func RunHttp(cmd *cobra.Command, args []string) (err error) {
 	fmt.Println(str)
 
 	if !opts.SendRequest {
+		// the jar as loaded, which makes --jar-out a plain conversion
+		return exportJar(jar, opts.JarOut)
+	}
+	// names in --out-dir are only known from the response, see ConsumeBody
+	if opts.NoClobber && opts.Out != "" && exists(opts.Out) {
+		PrintWarnings(cmd, []string{fmt.Sprintf("%s already exists, not sending the request", opts.Out)})
 		return nil
 	}
 
-	client := httpcore.NewClient()
-	resp, err := client.Send(req)
+	clientOpts, err := req.Settings().ClientOptions()
+	if err != nil {
+		return fmt.Errorf("configuring client: %w", err)
+	}
+	if jar != nil {
+		clientOpts = append(clientOpts, httpcore.WithCookieJar(jar))
+	}
+	clientOpts = append(clientOpts, httpcore.WithRetryNotify(func(a httpcore.Attempt) {
+		PrintWarnings(cmd, []string{a.String()})
+	}))
+
+	client := httpcore.NewClient(clientOpts...)
+	resp, err := client.Stream(cmd.Context(), req)
 	if err != nil {
 		return fmt.Errorf("sending request: %w", err)
 	}
+	defer resp.Close()
+
+	// e.g. a server without h2 support answers --http2 over HTTP/1.1
+	if res, sent := resp.ToHTTP(), req.ToHTTP(); res.ProtoMajor != sent.ProtoMajor {
+		PrintWarnings(cmd, []string{fmt.Sprintf("the server answered over %s instead of %s", res.Proto, sent.Proto)})
+	}
+
+	if jar != nil {
+		if err = jar.SaveFile(opts.Jar); err != nil {
+			return err
+		}
+		if err = exportJar(jar, opts.JarOut); err != nil {
+			return err
+		}
+	}
+
+	err = ConsumeBody(cmd, opts, client, req, resp, offset)
+	if err != nil {
+		return err
+	}
+
+	if opts.Har != "" {
+		creator := har.Creator{Name: "ghostman", Version: Version}
+		err = har.AppendToFile(opts.Har, creator, har.NewEntries(resp)...)
+		if err != nil {
+			return fmt.Errorf("saving HAR: %w", err)
+		}
+	}
+
+	resp.ShowTiming(opts.Timing == "tree")
+	resp.ShowTLS(opts.TLSInfo, opts.CertWarnDays)
+	resp.ShowProxy(opts.Verbose)
 
 	str, err = resp.ToString()
 	if err != nil {
@@ -92,49 +227,119 @@ func RunHttp(cmd *cobra.Command, args []string) (err error) {
 	}
 	fmt.Printf("\n%s\n", str)
 
-	if opts.Out != "" {
-		var exts []string
-		ext := filepath.Ext(opts.Out)
-		ct := resp.ContentType()
+	if opts.CertWarnDays > 0 {
+		PrintWarnings(cmd, resp.CertWarnings(opts.CertWarnDays))
+	}
 
-		exts, err = mime.ExtensionsByType(ct)
+	if opts.Timing == "json" && resp.Timing() != nil {
+		var data []byte
+		data, err = json.Marshal(resp.Timing())
 		if err != nil {
-			return fmt.Errorf("checking if file extension is suitable for the response body: %w", err)
+			return fmt.Errorf("encoding timing: %w", err)
 		}
+		fmt.Printf("\n%s\n", data)
+	}
 
-		if !slices.Contains(exts, ext) {
-			return fmt.Errorf("can't write %s into %s file", ct, ext)
-		}
+	return nil
+}
 
-		var f *os.File
-		f, err = os.OpenFile(opts.Out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o777)
-		if err != nil {
-			if os.IsPermission(err) {
-				return fmt.Errorf("opening file for writing: access denied")
+// exportJar writes jar to the --jar-out path, if one was given.
+func exportJar(jar *httpcore.CookieJar, path string) error {
+	if jar == nil || path == "" {
+		return nil
+	}
+	return jar.ExportFile(path)
+}
+
+// ConsumeBody streams the response body to the --out file and, with
+// --print-out, to stdout as it arrives, drawing a progress bar on stderr.
+// without either the body is only read for the summary. offset is the size
+// of a partial file the response continues.
+func ConsumeBody(cmd *cobra.Command, opts Options, client *httpcore.Client, req *httpcore.RequestConf, resp *httpcore.Response, offset int64) (err error) {
+	var dst []io.Writer
+	var f *os.File
+	var state *httpcore.ResumeState
+
+	target := OutPath(opts, resp)
+	if target != "" && opts.NoClobber && exists(target) {
+		PrintWarnings(cmd, []string{fmt.Sprintf("%s already exists, not overwriting it", target)})
+		target = ""
+	}
+
+	if target != "" {
+		f, err = OpenOut(cmd, opts, target, resp, offset)
+		if err != nil || f == nil {
+			return err
+		}
+		defer func() {
+			// closing twice after a successful download is harmless
+			f.Close()
+			if err != nil && state == nil {
+				os.Remove(f.Name())
 			}
-			return fmt.Errorf("opening file for writing: %w", err)
+		}()
+
+		// kept until the download completes, so an interrupted one can be
+		// continued. a decoded body can be neither resumed nor checked
+		// against Content-Length, which counts the encoded bytes
+		if code := resp.ToHTTP().StatusCode; (code == http.StatusOK || code == http.StatusPartialContent) && !resp.Decodes() {
+			s := httpcore.NewResumeState(req.ToHTTP().URL.String(), resp.ToHTTP())
+			if err = s.Save(target); err != nil {
+				return err
+			}
+			state = &s
 		}
-		defer f.Close()
 
-		err = resp.WriteBodyTo(f)
-		if err != nil {
-			return fmt.Errorf("writing response body to file: %w", err)
-		}
+		dst = append(dst, f)
 	}
 
 	if opts.PrintOut {
 		fmt.Println("\n==========BEGIN RESPONSE BODY==========")
-		err = resp.WriteBodyTo(os.Stdout)
-		if err != nil {
-			return fmt.Errorf("writing response body to stdout: %w", err)
+		dst = append(dst, os.Stdout)
+	}
+
+	// a bar would get mixed into a body printed on the same terminal
+	stderr := cmd.ErrOrStderr()
+	if !opts.NoProgress && isTerminal(stderr) && !(opts.PrintOut && isTerminal(os.Stdout)) {
+		bar := newProgressBar(stderr)
+		resp.OnProgress(bar.Update)
+		defer bar.Finish()
+	}
+
+	if opts.Segments > 1 && f != nil && resp.Segmentable() {
+		err = client.DownloadSegments(cmd.Context(), resp, f, opts.Segments)
+	} else {
+		err = resp.Consume(io.MultiWriter(dst...))
+	}
+	if opts.PrintOut {
+		fmt.Println("\n===========END RESPONSE BODY===========")
+	}
+	if err != nil {
+		if state != nil {
+			return fmt.Errorf("reading response body: %w; run again with --continue to resume", err)
 		}
-		fmt.Println("===========END RESPONSE BODY===========")
+		return fmt.Errorf("reading response body: %w", err)
 	}
 
-	return nil
+	if f == nil {
+		return nil
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("writing response body to file: %w", err)
+	}
+	if state != nil {
+		if err = checkDownload(target, *state); err != nil {
+			return err
+		}
+	}
+	return commitOut(target, opts)
 }
 
 func PreRunHttpFile(cmd *cobra.Command, args []string) error {
+	if httpfile.IsHTTPFile(args[0]) {
+		return PreRunRestFile(cmd, args)
+	}
+
 	buf := shared.BytesBuf()
 	defer shared.PutBytesBuf(buf)
 
@@ -149,25 +354,38 @@ func PreRunHttpFile(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	req, err := httpcore.NewRequestFromJSON(buf.Bytes())
+	ser, err := httpcore.DecodeRequest(buf.Bytes())
 	if err != nil {
 		return fmt.Errorf("malformed or invalid request file: %w", err)
 	}
 
-	b, ct, err := ParseAttachments(cmd)
-	if err != nil {
-		return fmt.Errorf("parsing attachments: %w", err)
-	}
+	return PreRunSerializable(cmd, ser, filepath.Dir(args[0]), nil, args[0])
+}
 
-	if b != nil && ct != "" {
-		req.SetBody(b, ct)
+// PreRunRestFile reads a .http file holding a single request. files with
+// several requests have to go through 'run' to pick one.
+func PreRunRestFile(cmd *cobra.Command, args []string) error {
+	coll, err := LoadCollection(cmd, args[0])
+	if err != nil {
+		return fmt.Errorf("malformed or invalid request file: %w", err)
 	}
 
-	err = ApplyRequestFlags(cmd, req)
-	if err != nil {
-		return fmt.Errorf("can't parse request flags: %w", err)
+	switch paths := coll.Paths(); len(paths) {
+	case 0:
+		return fmt.Errorf("no requests in %s", args[0])
+	case 1:
+		ser, err := coll.Resolve(paths[0])
+		if err != nil {
+			return err
+		}
+		return PreRunSerializable(cmd, ser, filepath.Dir(args[0]), coll.Variables, args[0])
+	default:
+		return fmt.Errorf("%s holds %d requests, pick one with 'ghostman run %s <request>': %s",
+			args[0], len(paths), args[0], strings.Join(paths, ", "))
 	}
+}
 
+func SetRequestContext(cmd *cobra.Command, req *httpcore.RequestConf) {
 	ctx := cmd.Context()
 	withVal := context.WithValue(ctx, ctxKeyHttpReq, req)
 
@@ -175,8 +393,6 @@ func PreRunHttpFile(cmd *cobra.Command, args []string) error {
 	withVal = context.WithValue(withVal, ctxKeyHttpOpts, opts)
 
 	cmd.SetContext(withVal)
-
-	return nil
 }
 
 func GetOptions(cmd *cobra.Command) Options {
@@ -185,6 +401,7 @@ func GetOptions(cmd *cobra.Command) Options {
 		SendRequest: true,
 		Out:         "",
 		PrintOut:    false,
+		Segments:    1,
 	}
 
 	if cmd.Flags().Changed("verbose") {
@@ -203,10 +420,108 @@ func GetOptions(cmd *cobra.Command) Options {
 		f, _ := cmd.Flags().GetBool("print-out")
 		opts.PrintOut = f
 	}
+	if cmd.Flags().Changed("out-dir") {
+		f, _ := cmd.Flags().GetString("out-dir")
+		opts.OutDir = f
+	}
+	if cmd.Flags().Changed("force-ext") {
+		f, _ := cmd.Flags().GetBool("force-ext")
+		opts.ForceExt = f
+	}
+	if cmd.Flags().Changed("no-clobber") {
+		f, _ := cmd.Flags().GetBool("no-clobber")
+		opts.NoClobber = f
+	}
+	if cmd.Flags().Changed("backup") {
+		f, _ := cmd.Flags().GetBool("backup")
+		opts.Backup = f
+	}
+	if cmd.Flags().Changed("continue") {
+		f, _ := cmd.Flags().GetBool("continue")
+		opts.Continue = f
+	}
+	if cmd.Flags().Changed("segments") {
+		f, _ := cmd.Flags().GetInt("segments")
+		opts.Segments = f
+	}
+	if cmd.Flags().Changed("no-progress") {
+		f, _ := cmd.Flags().GetBool("no-progress")
+		opts.NoProgress = f
+	}
+	if cmd.Flags().Changed("timing") {
+		f, _ := cmd.Flags().GetString("timing")
+		opts.Timing = strings.ToLower(f)
+	}
+	if cmd.Flags().Changed("tls-info") {
+		f, _ := cmd.Flags().GetBool("tls-info")
+		opts.TLSInfo = f
+	}
+	if opts.TLSInfo {
+		opts.CertWarnDays = httpcore.DefaultCertWarnDays
+	}
+	if cmd.Flags().Changed("cert-warn-days") {
+		f, _ := cmd.Flags().GetInt("cert-warn-days")
+		opts.CertWarnDays = f
+	}
+	if cmd.Flags().Changed("jar") {
+		f, _ := cmd.Flags().GetString("jar")
+		opts.Jar = f
+	}
+	if cmd.Flags().Changed("jar-out") {
+		f, _ := cmd.Flags().GetString("jar-out")
+		opts.JarOut = f
+	}
+	if cmd.Flags().Changed("har") {
+		f, _ := cmd.Flags().GetString("har")
+		opts.Har = f
+	}
+	if cmd.Flags().Changed("as") {
+		f, _ := cmd.Flags().GetString("as")
+		opts.As = strings.ToLower(f)
+	}
 
 	return opts
 }
 
+// LoadVars collects values for {{name}} placeholders. Process environment
+// has the lowest priority, then defaults from the request file itself,
+// then the --env file, then --var flags.
+func LoadVars(cmd *cobra.Command, dir string, defaults httpcore.Vars) (httpcore.Vars, error) {
+	vars := make(httpcore.Vars)
+	for _, kv := range os.Environ() {
+		k, v, _ := strings.Cut(kv, "=")
+		vars[k] = v
+	}
+	vars = vars.Merge(defaults)
+
+	if cmd.Flags().Changed("env") {
+		env, _ := cmd.Flags().GetString("env")
+		path, err := collection.FindEnv(env, dir, ".")
+		if err != nil {
+			return nil, err
+		}
+
+		fromFile, err := collection.LoadEnv(path)
+		if err != nil {
+			return nil, err
+		}
+		vars = vars.Merge(fromFile)
+	}
+
+	if cmd.Flags().Changed("var") {
+		args, _ := cmd.Flags().GetStringArray("var")
+		for _, arg := range args {
+			k, v, ok := strings.Cut(arg, "=")
+			if !ok {
+				return nil, fmt.Errorf("wrong variable syntax, expected key=value: %s", arg)
+			}
+			vars[strings.TrimSpace(k)] = v
+		}
+	}
+
+	return vars, nil
+}
+
 func ApplyRequestFlags(cmd *cobra.Command, req *httpcore.RequestConf) error {
 	if cmd.Flags().Changed("method") {
 		m, _ := cmd.Flags().GetString("method")
@@ -246,9 +561,146 @@ func ApplyRequestFlags(cmd *cobra.Command, req *httpcore.RequestConf) error {
 		}
 	}
 
+	settings, err := ParseSettingsFlags(cmd)
+	if err != nil {
+		return err
+	}
+	req.SetSettings(req.Settings().Merge(settings))
+
 	return nil
 }
 
+// ParseSettingsFlags collects the flags that change how a request is sent.
+// only flags given explicitly are set, so they override request files.
+func ParseSettingsFlags(cmd *cobra.Command) (s httpcore.Settings, err error) {
+	durations := []struct {
+		flag  string
+		field **httpcore.Duration
+	}{
+		{"connect-timeout", &s.ConnectTimeout},
+		{"tls-timeout", &s.TLSTimeout},
+		{"header-timeout", &s.HeaderTimeout},
+		{"max-time", &s.MaxTime},
+		{"retry-delay", &s.RetryDelay},
+		{"retry-max-time", &s.RetryMaxTime},
+	}
+
+	for _, d := range durations {
+		if !cmd.Flags().Changed(d.flag) {
+			continue
+		}
+		raw, _ := cmd.Flags().GetString(d.flag)
+		parsed, err := httpcore.ParseDuration(raw)
+		if err != nil {
+			return s, fmt.Errorf("invalid --%s: %w", d.flag, err)
+		}
+		*d.field = &parsed
+	}
+
+	versions := []struct {
+		flag    string
+		version string
+	}{
+		{"http1.1", httpcore.HTTP11},
+		{"http2", httpcore.HTTP2},
+		{"http2-prior-knowledge", httpcore.HTTP2PriorKnowledge},
+	}
+	for _, v := range versions {
+		if f, _ := cmd.Flags().GetBool(v.flag); !f {
+			continue
+		}
+		if s.HTTPVersion != "" {
+			return s, fmt.Errorf("only one of --http1.1, --http2 and --http2-prior-knowledge can be used")
+		}
+		s.HTTPVersion = v.version
+	}
+
+	ipv4, _ := cmd.Flags().GetBool("ipv4")
+	ipv6, _ := cmd.Flags().GetBool("ipv6")
+	switch {
+	case ipv4 && ipv6:
+		return s, fmt.Errorf("only one of --ipv4 and --ipv6 can be used")
+	case ipv4:
+		s.IPVersion = "4"
+	case ipv6:
+		s.IPVersion = "6"
+	}
+
+	if cmd.Flags().Changed("resolve") {
+		s.Resolve, _ = cmd.Flags().GetStringArray("resolve")
+		for _, r := range s.Resolve {
+			if _, err := httpcore.ParseResolve(r); err != nil {
+				return s, fmt.Errorf("invalid --resolve: %w", err)
+			}
+		}
+	}
+	if cmd.Flags().Changed("connect-to") {
+		s.ConnectTo, _ = cmd.Flags().GetStringArray("connect-to")
+		for _, r := range s.ConnectTo {
+			if _, err := httpcore.ParseConnectTo(r); err != nil {
+				return s, fmt.Errorf("invalid --connect-to: %w", err)
+			}
+		}
+	}
+
+	if cmd.Flags().Changed("follow") {
+		f, _ := cmd.Flags().GetBool("follow")
+		s.Follow = &f
+	}
+	if cmd.Flags().Changed("max-redirs") {
+		f, _ := cmd.Flags().GetInt("max-redirs")
+		if f < 0 {
+			return s, fmt.Errorf("invalid --max-redirs: must not be negative")
+		}
+		s.MaxRedirects = &f
+	}
+	if cmd.Flags().Changed("location-trusted") {
+		f, _ := cmd.Flags().GetBool("location-trusted")
+		s.LocationTrusted = &f
+	}
+	if cmd.Flags().Changed("insecure") {
+		f, _ := cmd.Flags().GetBool("insecure")
+		s.Insecure = &f
+	}
+	if cmd.Flags().Changed("retry") {
+		f, _ := cmd.Flags().GetInt("retry")
+		if f < 0 {
+			return s, fmt.Errorf("invalid --retry: must not be negative")
+		}
+		s.Retry = &f
+	}
+	if cmd.Flags().Changed("compressed") {
+		f, _ := cmd.Flags().GetBool("compressed")
+		s.Compressed = &f
+	}
+
+	strs := []struct {
+		flag  string
+		field *string
+	}{
+		{"cert", &s.Cert},
+		{"key", &s.Key},
+		{"pass", &s.CertPassword},
+		{"cacert", &s.CACert},
+		{"capath", &s.CAPath},
+		{"tls-min", &s.TLSMin},
+		{"tls-max", &s.TLSMax},
+		{"servername", &s.ServerName},
+		{"proxy", &s.Proxy},
+		{"noproxy", &s.NoProxy},
+		{"retry-on", &s.RetryOn},
+		{"dns-server", &s.DNSServers},
+		{"unix-socket", &s.UnixSocket},
+	}
+	for _, f := range strs {
+		if cmd.Flags().Changed(f.flag) {
+			*f.field, _ = cmd.Flags().GetString(f.flag)
+		}
+	}
+
+	return s, nil
+}
+
 // works with both headers and query parameters
 func ParseKeyValues(h []string) (map[string][]string, error) {
 	// example: -H "Accept:application/json,text/plain"
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bigelle/ghostman/internal/curl"
+	"github.com/bigelle/ghostman/internal/har"
+	"github.com/bigelle/ghostman/internal/httpcore"
+	"github.com/bigelle/ghostman/internal/openapi"
+	"github.com/bigelle/ghostman/internal/postman"
+	"github.com/spf13/cobra"
+)
+
+var ImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "convert requests from other tools into ghostman request files",
+}
+
+var ImportCurlCmd = &cobra.Command{
+	Use:   "curl [command]",
+	Short: "convert a curl command line into a request file",
+	Long: "convert a curl command line into a request file. " +
+		"pass the whole command as a single quoted argument, after '--', or through stdin",
+	RunE: RunImportCurl,
+}
+
+var ImportHarCmd = &cobra.Command{
+	Use:   "har <file>",
+	Short: "convert every request captured in a HAR file into a collection",
+	Args:  cobra.ExactArgs(1),
+	RunE:  RunImportHar,
+}
+
+var ImportPostmanCmd = &cobra.Command{
+	Use:   "postman <file>",
+	Short: "convert a Postman v2.1 collection or environment export",
+	Long: "convert a Postman v2.1 collection into a ghostman collection, " +
+		"or a Postman environment into an environment file for --env",
+	Args: cobra.ExactArgs(1),
+	RunE: RunImportPostman,
+}
+
+var ImportOpenAPICmd = &cobra.Command{
+	Use:   "openapi <spec>",
+	Short: "generate a collection with a request per operation from an OpenAPI 3 spec",
+	Long: "generate a collection with a request per operation from an OpenAPI 3 spec in YAML or JSON. " +
+		"the server URL is stored in the {{baseUrl}} variable, path parameters become variables",
+	Args: cobra.ExactArgs(1),
+	RunE: RunImportOpenAPI,
+}
+
+func init() {
+	ImportCmd.PersistentFlags().String("save", "", "write the imported request into a file instead of stdout")
+
+	ImportCurlCmd.Flags().Bool("send", false, "send the imported request instead of printing it")
+
+	ImportCmd.AddCommand(ImportCurlCmd)
+	ImportCmd.AddCommand(ImportHarCmd)
+	ImportCmd.AddCommand(ImportPostmanCmd)
+	ImportCmd.AddCommand(ImportOpenAPICmd)
+	RootCmd.AddCommand(ImportCmd)
+}
+
+func RunImportCurl(cmd *cobra.Command, args []string) error {
+	var argv []string
+	var err error
+
+	switch len(args) {
+	case 0:
+		var b []byte
+		b, err = io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return fmt.Errorf("reading curl command from stdin: %w", err)
+		}
+		argv, err = curl.SplitCommand(string(b))
+	case 1:
+		argv, err = curl.SplitCommand(args[0])
+	default:
+		argv = args
+	}
+	if err != nil {
+		return fmt.Errorf("splitting curl command: %w", err)
+	}
+
+	ser, warnings, err := curl.Parse(argv)
+	if err != nil {
+		return fmt.Errorf("parsing curl command: %w", err)
+	}
+	PrintWarnings(cmd, warnings)
+
+	if send, _ := cmd.Flags().GetBool("send"); send {
+		req, err := httpcore.NewRequestFromSerializable(ser)
+		if err != nil {
+			return fmt.Errorf("building request: %w", err)
+		}
+
+		err = ApplyRequestFlags(cmd, req)
+		if err != nil {
+			return fmt.Errorf("can't parse request flags: %w", err)
+		}
+
+		SetRequestContext(cmd, req)
+		return RunHttp(cmd, args)
+	}
+
+	return SaveImported(cmd, ser)
+}
+
+func RunImportHar(cmd *cobra.Command, args []string) error {
+	h, err := har.LoadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0]))
+	coll, warnings, err := h.ToCollection(name)
+	if err != nil {
+		return fmt.Errorf("converting HAR: %w", err)
+	}
+	PrintWarnings(cmd, warnings)
+
+	return SaveImported(cmd, coll)
+}
+
+func RunImportPostman(cmd *cobra.Command, args []string) error {
+	pc, env, err := postman.LoadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	if env != nil {
+		return SaveImported(cmd, env.ToVars())
+	}
+
+	coll, warnings := pc.ToCollection()
+	PrintWarnings(cmd, warnings)
+
+	return SaveImported(cmd, coll)
+}
+
+func RunImportOpenAPI(cmd *cobra.Command, args []string) error {
+	spec, err := openapi.LoadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	coll, warnings := spec.ToCollection()
+	PrintWarnings(cmd, warnings)
+
+	return SaveImported(cmd, coll)
+}
+
+// SaveImported writes v as indented JSON into the --save file or stdout.
+func SaveImported(cmd *cobra.Command, v any) error {
+	var w io.Writer = cmd.OutOrStdout()
+
+	if path, _ := cmd.Flags().GetString("save"); path != "" {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+		if err != nil {
+			return fmt.Errorf("opening file for writing: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("writing imported request: %w", err)
+	}
+
+	return nil
+}
+
+func PrintWarnings(cmd *cobra.Command, warnings []string) {
+	for _, w := range warnings {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s\n", strings.TrimSpace(w))
+	}
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bigelle/ghostman/internal/httpcore"
+)
+
+const (
+	progressInterval = 200 * time.Millisecond
+	progressWidth    = 24
+)
+
+// progressBar draws a transfer on one terminal line with its rate and ETA,
+// redrawn at most every progressInterval.
+type progressBar struct {
+	w       io.Writer
+	started time.Time
+	drawn   time.Time
+
+	received, total int64
+}
+
+func newProgressBar(w io.Writer) *progressBar {
+	return &progressBar{w: w, started: time.Now(), total: -1}
+}
+
+func (p *progressBar) Update(received, total int64) {
+	p.received, p.total = received, total
+	if now := time.Now(); now.Sub(p.drawn) >= progressInterval {
+		p.drawn = now
+		p.draw(now)
+	}
+}
+
+// Finish draws the final state and ends the line.
+func (p *progressBar) Finish() {
+	if p.drawn.IsZero() {
+		// finished before the first redraw, nothing to clean up
+		return
+	}
+	p.draw(time.Now())
+	fmt.Fprintln(p.w)
+}
+
+func (p *progressBar) draw(now time.Time) {
+	elapsed := now.Sub(p.started).Seconds()
+	var rate float64
+	if elapsed > 0 {
+		rate = float64(p.received) / elapsed
+	}
+
+	line := fmt.Sprintf("%s  %s/s", httpcore.FormatBytes(p.received), httpcore.FormatBytes(int64(rate)))
+	if p.total > 0 {
+		done := min(float64(p.received)/float64(p.total), 1)
+		filled := int(done * progressWidth)
+		bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressWidth-filled)
+
+		eta := "--"
+		if rate > 0 {
+			eta = time.Duration(float64(p.total-p.received) / rate * float64(time.Second)).Round(time.Second).String()
+		}
+
+		line = fmt.Sprintf("[%s] %3.0f%%  %s of %s  %s/s  ETA %s", bar, done*100,
+			httpcore.FormatBytes(p.received), httpcore.FormatBytes(p.total), httpcore.FormatBytes(int64(rate)), eta)
+	}
+
+	// pad over what's left of a longer previous line
+	fmt.Fprintf(p.w, "\r%-80s", line)
+}
+
+// isTerminal tells whether w is a terminal rather than a file or a pipe.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -1,12 +1,18 @@
 package cmd
 
 import (
+	"context"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/bigelle/ghostman/internal/httpcore"
 	"github.com/spf13/cobra"
 )
 
+// Version is set at build time with -ldflags "-X github.com/bigelle/ghostman/cmd.Version=..."
+var Version = "dev"
+
 var RootCmd = &cobra.Command{
 	Use:     "ghostman",
 	Short:   "deez nuts",
@@ -16,8 +22,13 @@ var RootCmd = &cobra.Command{
 }
 
 func Execute() {
-	err := RootCmd.Execute()
+	// Ctrl-C cancels the context, aborting a request in flight
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := RootCmd.ExecuteContext(ctx)
 	if err != nil {
+		stop()
 		os.Exit(1)
 	}
 }
@@ -40,14 +51,249 @@ func init() {
 		"",
 		"set the output for the request. pass 'stdout' to print into stdout",
 	)
+	RootCmd.PersistentFlags().String(
+		"out-dir",
+		"",
+		"save the response body in this directory, named after Content-Disposition or the URL path",
+	)
+	RootCmd.PersistentFlags().Bool(
+		"force-ext",
+		false,
+		"write the body into --out even if its extension doesn't match the content type",
+	)
+	RootCmd.PersistentFlags().Bool(
+		"no-clobber",
+		false,
+		"don't overwrite an existing output file",
+	)
+	RootCmd.PersistentFlags().Bool(
+		"backup",
+		false,
+		"keep an existing output file as <file>"+BackupSuffix+" before replacing it",
+	)
 	RootCmd.PersistentFlags().Bool(
 		"print-out",
 		false,
 		"print response body into stdout",
 	)
+	RootCmd.PersistentFlags().String(
+		"har",
+		"",
+		"append every request sent, redirects and retried attempts included, and its response to a HAR file",
+	)
+	RootCmd.PersistentFlags().String(
+		"connect-timeout",
+		"",
+		"maximum time to establish the connection, in seconds or with a unit like 500ms. 0 disables it (default 8s)",
+	)
+	RootCmd.PersistentFlags().String(
+		"tls-timeout",
+		"",
+		"maximum time for the TLS handshake, in seconds or with a unit. 0 disables it (default 8s)",
+	)
+	RootCmd.PersistentFlags().String(
+		"header-timeout",
+		"",
+		"maximum time to wait for response headers once the request is sent, in seconds or with a unit. 0 disables it (default 15s)",
+	)
+	RootCmd.PersistentFlags().String(
+		"max-time",
+		"",
+		"maximum time for the whole request including the response body, in seconds or with a unit. unlimited by default",
+	)
+	RootCmd.PersistentFlags().BoolP(
+		"follow",
+		"L",
+		false,
+		"follow redirects. every hop is listed in the response",
+	)
+	RootCmd.PersistentFlags().Int(
+		"max-redirs",
+		httpcore.DefaultMaxRedirects,
+		"maximum number of redirects to follow with --follow",
+	)
+	RootCmd.PersistentFlags().Bool(
+		"location-trusted",
+		false,
+		"keep sending Authorization and Cookie headers when a redirect leads to another host",
+	)
+	RootCmd.PersistentFlags().String(
+		"cert",
+		"",
+		"client certificate for mutual TLS: a PEM file, holding the key unless --key is given, or a PKCS#12 (.p12/.pfx) bundle",
+	)
+	RootCmd.PersistentFlags().String(
+		"key",
+		"",
+		"PEM private key for the --cert certificate",
+	)
+	RootCmd.PersistentFlags().String(
+		"pass",
+		"",
+		"password of a PKCS#12 --cert bundle",
+	)
+	RootCmd.PersistentFlags().String(
+		"cacert",
+		"",
+		"PEM bundle of CA certificates to verify the server with, instead of the system ones",
+	)
+	RootCmd.PersistentFlags().String(
+		"capath",
+		"",
+		"directory of PEM CA certificates to verify the server with, instead of the system ones",
+	)
+	RootCmd.PersistentFlags().BoolP(
+		"insecure",
+		"k",
+		false,
+		"don't verify the server certificate",
+	)
+	RootCmd.PersistentFlags().String(
+		"tls-min",
+		"",
+		"minimum TLS version: 1.0, 1.1, 1.2 or 1.3 (default 1.2)",
+	)
+	RootCmd.PersistentFlags().String(
+		"tls-max",
+		"",
+		"maximum TLS version: 1.0, 1.1, 1.2 or 1.3 (default 1.3)",
+	)
+	RootCmd.PersistentFlags().String(
+		"servername",
+		"",
+		"server name to send in SNI and verify the certificate against, instead of the URL host",
+	)
+	RootCmd.PersistentFlags().StringP(
+		"proxy",
+		"x",
+		"",
+		"send requests through this proxy: http://, https:// or socks5://, with credentials as user:password@host. by default HTTP_PROXY, HTTPS_PROXY and NO_PROXY are used",
+	)
+	RootCmd.PersistentFlags().String(
+		"noproxy",
+		"",
+		"comma separated hosts, domains or CIDR ranges to reach without the proxy, replaces NO_PROXY. \"*\" disables proxies",
+	)
+	RootCmd.PersistentFlags().Int(
+		"retry",
+		0,
+		"send a failed request again up to this many times, waiting a second and doubling the wait each time. Retry-After headers are honoured",
+	)
+	RootCmd.PersistentFlags().String(
+		"retry-delay",
+		"",
+		"fixed wait between retries instead of the doubling one, e.g. 500ms or 2 (seconds)",
+	)
+	RootCmd.PersistentFlags().String(
+		"retry-max-time",
+		"",
+		"stop retrying once this much time passed since the first attempt",
+	)
+	RootCmd.PersistentFlags().String(
+		"retry-on",
+		"",
+		"comma separated failures to retry: connect, 4xx, 5xx or status codes (default \""+httpcore.DefaultRetryOn+"\")",
+	)
+	RootCmd.PersistentFlags().Bool(
+		"compressed",
+		false,
+		"ask for a compressed response and decode gzip, deflate, br and zstd bodies",
+	)
+	RootCmd.PersistentFlags().Bool(
+		"http1.1",
+		false,
+		"use HTTP/1.1 only, the default",
+	)
+	RootCmd.PersistentFlags().Bool(
+		"http2",
+		false,
+		"use HTTP/2 over TLS when the server supports it, negotiated with ALPN",
+	)
+	RootCmd.PersistentFlags().Bool(
+		"http2-prior-knowledge",
+		false,
+		"use HTTP/2 right away without negotiating, cleartext h2c for http:// URLs",
+	)
+	RootCmd.PersistentFlags().StringArray(
+		"resolve",
+		[]string{},
+		"connect to an address instead of resolving the host, as host:port:addr[,addr]. port may be *, can be repeated",
+	)
+	RootCmd.PersistentFlags().StringArray(
+		"connect-to",
+		[]string{},
+		"connect to another host and port, as host:port:connect-to-host:connect-to-port. Host and SNI stay the same, can be repeated",
+	)
+	RootCmd.PersistentFlags().String(
+		"dns-server",
+		"",
+		"comma separated DNS servers to resolve hosts with instead of the system resolver, as addr or addr:port",
+	)
+	RootCmd.PersistentFlags().BoolP(
+		"ipv4",
+		"4",
+		false,
+		"only connect over IPv4",
+	)
+	RootCmd.PersistentFlags().BoolP(
+		"ipv6",
+		"6",
+		false,
+		"only connect over IPv6",
+	)
+	RootCmd.PersistentFlags().String(
+		"unix-socket",
+		"",
+		"send requests through this Unix socket instead of the network, keeping the URL's host and path",
+	)
+	RootCmd.PersistentFlags().Bool(
+		"tls-info",
+		false,
+		"show the TLS version, cipher, ALPN, OCSP stapling and the server certificate chain",
+	)
+	RootCmd.PersistentFlags().Int(
+		"cert-warn-days",
+		httpcore.DefaultCertWarnDays,
+		"warn when a certificate of the chain expires within this many days. on by default with --tls-info, 0 disables it",
+	)
+	RootCmd.PersistentFlags().String(
+		"timing",
+		"",
+		"show how long DNS, connect, TLS, waiting for the server and the transfer took. --timing=json prints it as JSON",
+	)
+	RootCmd.PersistentFlags().Lookup("timing").NoOptDefVal = "tree"
+	RootCmd.PersistentFlags().String(
+		"jar",
+		"",
+		"cookie jar file. matching cookies are sent with the request and the ones set by the response are saved back. Netscape cookies.txt files, as used by curl and browser extensions, are detected and kept in that format",
+	)
+	RootCmd.PersistentFlags().String(
+		"jar-out",
+		"",
+		"also write the --jar cookies to this file, as Netscape cookies.txt if it ends with .txt and as JSON otherwise, e.g. to hand them to curl. with --send-request=false the jar is only converted",
+	)
+	RootCmd.PersistentFlags().String(
+		"as",
+		"",
+		"print the request in another format instead of sending it. supported: curl",
+	)
+
+	RootCmd.PersistentFlags().String(
+		"env",
+		"",
+		"environment file or name (looks up <name>.env.json) with values for {{variables}} in request files",
+	)
+	RootCmd.PersistentFlags().StringArray(
+		"var",
+		[]string{},
+		"set a value for a {{variable}} in request files as key=value. takes priority over --env",
+	)
 
 	RootCmd.PersistentFlags().BoolP("verbose", "v", false, "dump the whole request")
 	RootCmd.PersistentFlags().Bool("send-request", true, "send request")
+	RootCmd.PersistentFlags().Bool("continue", false, "resume a partial --out file with a Range request, if the resource didn't change")
+	RootCmd.PersistentFlags().Int("segments", 1, "download the --out or --out-dir file over this many connections, if the server supports ranges")
+	RootCmd.PersistentFlags().Bool("no-progress", false, "don't draw a progress bar on stderr while the body downloads")
 	RootCmd.PersistentFlags().Bool("sanitize-cookies", true, "omits empty or malformed cookies")
 	RootCmd.PersistentFlags().Bool("sanitize-headers", true, "omits empty or malformed headers")
 	RootCmd.PersistentFlags().Bool("sanitize-query", true, "omits empty or malformed query parameters")
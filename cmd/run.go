@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/bigelle/ghostman/internal/collection"
+	"github.com/bigelle/ghostman/internal/httpcore"
+	"github.com/bigelle/ghostman/internal/httpfile"
+	"github.com/spf13/cobra"
+)
+
+var RunCmd = &cobra.Command{
+	Use:     "run <collection> <request>",
+	Short:   "send a named request from a collection file",
+	Long:    "send a named request from a collection file. nested requests are addressed by their path, e.g. 'admin/users/list'. .http and .rest files are read as collections of their requests",
+	Args:    cobra.ExactArgs(2),
+	PreRunE: PreRunCollection,
+	RunE:    RunHttp,
+}
+
+func init() {
+	RootCmd.AddCommand(RunCmd)
+}
+
+func PreRunCollection(cmd *cobra.Command, args []string) error {
+	coll, err := LoadCollection(cmd, args[0])
+	if err != nil {
+		return fmt.Errorf("loading collection: %w", err)
+	}
+
+	ser, err := coll.Resolve(args[1])
+	if err != nil {
+		return err
+	}
+
+	return PreRunSerializable(cmd, ser, filepath.Dir(args[0]), coll.Variables, args[1])
+}
+
+// LoadCollection reads a collection file, or a .http file as a collection of
+// its requests.
+func LoadCollection(cmd *cobra.Command, path string) (*collection.Collection, error) {
+	if !httpfile.IsHTTPFile(path) {
+		return collection.LoadFile(path)
+	}
+
+	coll, warnings, err := httpfile.LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	PrintWarnings(cmd, warnings)
+
+	return coll, nil
+}
+
+// PreRunSerializable resolves variables in a decoded request, applies
+// attachments and request flags and stores the result in the command context.
+func PreRunSerializable(cmd *cobra.Command, ser httpcore.RequestSerializable, dir string, defaults httpcore.Vars, name string) error {
+	vars, err := LoadVars(cmd, dir, defaults)
+	if err != nil {
+		return fmt.Errorf("loading variables: %w", err)
+	}
+
+	ser, err = ser.Interpolate(vars)
+	if err != nil {
+		return fmt.Errorf("resolving variables in %s: %w", name, err)
+	}
+
+	req, err := httpcore.NewRequestFromSerializable(ser)
+	if err != nil {
+		return fmt.Errorf("building request %s: %w", name, err)
+	}
+
+	b, ct, err := ParseAttachments(cmd)
+	if err != nil {
+		return fmt.Errorf("parsing attachments: %w", err)
+	}
+
+	if b != nil && ct != "" {
+		req.SetBody(b, ct)
+	}
+
+	err = ApplyRequestFlags(cmd, req)
+	if err != nil {
+		return fmt.Errorf("can't parse request flags: %w", err)
+	}
+
+	SetRequestContext(cmd, req)
+
+	return nil
+}
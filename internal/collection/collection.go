@@ -0,0 +1,242 @@
+package collection
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"strings"
+
+	"github.com/bigelle/ghostman/internal/httpcore"
+)
+
+// Collection is a file with many named requests, optionally grouped into
+// nested folders. Requests are addressed by their path, e.g. "admin/users/list".
+type Collection struct {
+	Folder
+	Variables httpcore.Vars `json:"variables,omitempty"`
+}
+
+type Folder struct {
+	Name     string    `json:"name"`
+	Defaults *Defaults `json:"defaults,omitempty"`
+	Folders  []Folder  `json:"folders,omitempty"`
+	Requests []Request `json:"requests,omitempty"`
+}
+
+type Request struct {
+	Name string `json:"name"`
+	httpcore.RequestSerializable
+}
+
+// Defaults are inherited by every request in a folder and its subfolders.
+// Values set closer to the request win.
+type Defaults struct {
+	QueryParams map[string][]string `json:"query_params,omitempty"`
+	Headers     map[string][]string `json:"headers,omitempty"`
+	Cookies     []httpcore.Cookie   `json:"cookies,omitempty"`
+	Auth        *httpcore.AuthSpec  `json:"auth,omitempty"`
+}
+
+func Load(r io.Reader) (*Collection, error) {
+	var c Collection
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("decoding collection: %w", err)
+	}
+
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+func LoadFile(path string) (*Collection, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening collection file: %w", err)
+	}
+	defer f.Close()
+
+	return Load(f)
+}
+
+func (c Collection) Save(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
+
+// Resolve finds the request by its slash-separated path and merges the
+// defaults of every folder on the way into it.
+func (c Collection) Resolve(path string) (httpcore.RequestSerializable, error) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	name := parts[len(parts)-1]
+
+	folder := &c.Folder
+	chain := []*Defaults{folder.Defaults}
+
+	for _, part := range parts[:len(parts)-1] {
+		next := folder.folder(part)
+		if next == nil {
+			return httpcore.RequestSerializable{}, c.notFound(path)
+		}
+		folder = next
+		chain = append(chain, folder.Defaults)
+	}
+
+	req := folder.request(name)
+	if req == nil {
+		return httpcore.RequestSerializable{}, c.notFound(path)
+	}
+
+	ser := req.RequestSerializable
+	for i := len(chain) - 1; i >= 0; i-- {
+		ser = chain[i].apply(ser)
+	}
+
+	return ser, nil
+}
+
+// Paths lists every request in the collection in file order.
+func (c Collection) Paths() []string {
+	return c.Folder.paths("")
+}
+
+func (c Collection) notFound(path string) error {
+	return fmt.Errorf("no request %q in collection; available: %s", path, strings.Join(c.Paths(), ", "))
+}
+
+func (c Collection) validate() error {
+	seen := make(map[string]bool)
+	return c.Folder.validate("", seen)
+}
+
+func (f *Folder) folder(name string) *Folder {
+	for i := range f.Folders {
+		if f.Folders[i].Name == name {
+			return &f.Folders[i]
+		}
+	}
+	return nil
+}
+
+func (f *Folder) request(name string) *Request {
+	for i := range f.Requests {
+		if f.Requests[i].Name == name {
+			return &f.Requests[i]
+		}
+	}
+	return nil
+}
+
+func (f Folder) paths(prefix string) []string {
+	var paths []string
+	for _, r := range f.Requests {
+		paths = append(paths, prefix+r.Name)
+	}
+	for _, sub := range f.Folders {
+		paths = append(paths, sub.paths(prefix+sub.Name+"/")...)
+	}
+	return paths
+}
+
+func (f Folder) validate(prefix string, seen map[string]bool) error {
+	for _, r := range f.Requests {
+		if r.Name == "" || strings.Contains(r.Name, "/") {
+			return fmt.Errorf("invalid request name %q in %q: must be non-empty and contain no '/'", r.Name, prefix)
+		}
+		if seen[prefix+r.Name] {
+			return fmt.Errorf("duplicate request %q", prefix+r.Name)
+		}
+		seen[prefix+r.Name] = true
+	}
+	for _, sub := range f.Folders {
+		if sub.Name == "" || strings.Contains(sub.Name, "/") {
+			return fmt.Errorf("invalid folder name %q in %q: must be non-empty and contain no '/'", sub.Name, prefix)
+		}
+		if err := sub.validate(prefix+sub.Name+"/", seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UniqueName returns name, or if it's taken already, name numbered with
+// format, e.g. "%s-%d", trying higher numbers until one is free. seen holds
+// every name handed out among the siblings, generated ones included, and
+// is updated, so importers never produce duplicate paths.
+func UniqueName(name, format string, seen map[string]int) string {
+	if seen[name] == 0 {
+		seen[name] = 1
+		return name
+	}
+	for {
+		seen[name]++
+		unique := fmt.Sprintf(format, name, seen[name])
+		if seen[unique] == 0 {
+			seen[unique] = 1
+			return unique
+		}
+	}
+}
+
+// apply fills in everything the request doesn't set itself.
+func (d *Defaults) apply(ser httpcore.RequestSerializable) httpcore.RequestSerializable {
+	if d == nil {
+		return ser
+	}
+
+	ser.Headers = mergeValues(d.Headers, ser.Headers, textproto.CanonicalMIMEHeaderKey)
+	ser.QueryParams = mergeValues(d.QueryParams, ser.QueryParams, func(s string) string { return s })
+
+	if len(d.Cookies) != 0 {
+		cookies := make([]httpcore.Cookie, 0, len(d.Cookies)+len(ser.Cookies))
+		for _, c := range d.Cookies {
+			if !hasCookie(ser.Cookies, c.Name) {
+				cookies = append(cookies, c)
+			}
+		}
+		ser.Cookies = append(cookies, ser.Cookies...)
+	}
+
+	if ser.Auth == nil && d.Auth != nil {
+		auth := *d.Auth
+		ser.Auth = &auth
+	}
+
+	return ser
+}
+
+func mergeValues(defaults, own map[string][]string, canon func(string) string) map[string][]string {
+	if len(defaults) == 0 {
+		return own
+	}
+
+	merged := make(map[string][]string, len(defaults)+len(own))
+	set := make(map[string]bool, len(own))
+	for k, v := range own {
+		merged[k] = v
+		set[canon(k)] = true
+	}
+	for k, v := range defaults {
+		if !set[canon(k)] {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+func hasCookie(cookies []httpcore.Cookie, name string) bool {
+	for _, c := range cookies {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
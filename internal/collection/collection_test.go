@@ -0,0 +1,105 @@
+package collection
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bigelle/ghostman/internal/httpcore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCollection = `{
+  "name": "users",
+  "defaults": {
+    "headers": {"Accept": ["application/json"], "X-Team": ["core"]},
+    "query_params": {"version": ["1"]},
+    "cookies": [{"name": "session", "value": "root"}]
+  },
+  "requests": [
+    {"name": "health", "method": "GET", "url": "https://example.com/health"}
+  ],
+  "folders": [
+    {
+      "name": "admin",
+      "defaults": {
+        "headers": {"x-team": ["admin"]},
+        "cookies": [{"name": "session", "value": "admin"}]
+      },
+      "requests": [
+        {
+          "name": "list",
+          "method": "GET",
+          "url": "https://example.com/users",
+          "query_params": {"version": ["2"]},
+          "headers": {"Accept": ["text/plain"]}
+        }
+      ]
+    }
+  ]
+}`
+
+func TestCollection_Resolve(t *testing.T) {
+	c, err := Load(strings.NewReader(testCollection))
+	require.NoError(t, err)
+
+	testcases := []struct {
+		Name     string
+		Path     string
+		Expected httpcore.RequestSerializable
+	}{
+		{
+			Name: "root request inherits root defaults",
+			Path: "health",
+			Expected: httpcore.RequestSerializable{
+				Method:      "GET",
+				URL:         "https://example.com/health",
+				QueryParams: map[string][]string{"version": {"1"}},
+				Headers:     map[string][]string{"Accept": {"application/json"}, "X-Team": {"core"}},
+				Cookies:     []httpcore.Cookie{{Name: "session", Value: "root"}},
+			},
+		},
+		{
+			Name: "nested request overrides defaults",
+			Path: "admin/list",
+			Expected: httpcore.RequestSerializable{
+				Method:      "GET",
+				URL:         "https://example.com/users",
+				QueryParams: map[string][]string{"version": {"2"}},
+				Headers:     map[string][]string{"Accept": {"text/plain"}, "x-team": {"admin"}},
+				Cookies:     []httpcore.Cookie{{Name: "session", Value: "admin"}},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ser, err := c.Resolve(tc.Path)
+			require.NoError(t, err)
+			assert.Equal(t, tc.Expected, ser)
+		})
+	}
+}
+
+func TestCollection_ResolveMissing(t *testing.T) {
+	c, err := Load(strings.NewReader(testCollection))
+	require.NoError(t, err)
+
+	_, err = c.Resolve("admin/delete")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "health, admin/list")
+}
+
+func TestCollection_LoadDuplicate(t *testing.T) {
+	_, err := Load(strings.NewReader(`{"requests": [{"name": "a", "url": "x"}, {"name": "a", "url": "y"}]}`))
+	assert.Error(t, err)
+}
+
+func TestUniqueName(t *testing.T) {
+	seen := make(map[string]int)
+	var names []string
+	for _, n := range []string{"a", "a", "a-2", "a", "a-3"} {
+		names = append(names, UniqueName(n, "%s-%d", seen))
+	}
+	assert.Equal(t, []string{"a", "a-2", "a-2-2", "a-3", "a-3-2"}, names)
+}
@@ -0,0 +1,58 @@
+package collection
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/bigelle/ghostman/internal/httpcore"
+)
+
+// EnvFileSuffix is appended to environment names when looking them up,
+// so that --env staging finds staging.env.json.
+const EnvFileSuffix = ".env.json"
+
+// LoadEnv reads an environment file: a flat JSON object of string values.
+func LoadEnv(path string) (httpcore.Vars, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening environment file: %w", err)
+	}
+	defer f.Close()
+
+	vars := make(httpcore.Vars)
+
+	dec := json.NewDecoder(f)
+	if err = dec.Decode(&vars); err != nil {
+		return nil, fmt.Errorf("decoding environment file %s: %w", path, err)
+	}
+
+	return vars, nil
+}
+
+// FindEnv treats env as a path first, then as a name looked up in every
+// directory from dirs.
+func FindEnv(env string, dirs ...string) (string, error) {
+	candidates := []string{env}
+	for _, dir := range dirs {
+		candidates = append(candidates, filepath.Join(dir, env+EnvFileSuffix))
+	}
+
+	for _, path := range candidates {
+		info, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("looking up environment %s: %w", env, err)
+		}
+		if !info.IsDir() {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("environment %s not found, tried: %v", env, candidates)
+}
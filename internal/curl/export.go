@@ -0,0 +1,258 @@
+package curl
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bigelle/ghostman/internal/httpcore"
+	"github.com/bigelle/ghostman/internal/shared"
+)
+
+// headers curl computes on its own
+var skipHeaders = []string{"Host", "Content-Length", "Cookie"}
+
+// Command renders the request as an equivalent curl command line.
+func Command(req *httpcore.RequestConf) (string, error) {
+	body, err := req.BodyBytes()
+	if err != nil {
+		return "", err
+	}
+
+	r := req.ToHTTP()
+
+	buf := shared.StringBuilder()
+	defer shared.PutStringBuilder(buf)
+
+	args := [][]string{}
+
+	switch {
+	case r.Method == http.MethodHead:
+		args = append(args, []string{"-I"})
+	case r.Method == http.MethodGet && len(body) == 0:
+	case r.Method == http.MethodPost && len(body) != 0:
+	default:
+		args = append(args, []string{"-X", r.Method})
+	}
+
+	if r.Host != "" && r.Host != r.URL.Host {
+		args = append(args, []string{"-H", "Host: " + r.Host})
+	}
+
+	keys := make([]string, 0, len(r.Header))
+	for k := range r.Header {
+		if !slices.Contains(skipHeaders, k) {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		for _, v := range r.Header[k] {
+			args = append(args, []string{"-H", k + ": " + v})
+		}
+	}
+
+	if cookies := r.Header.Values("Cookie"); len(cookies) != 0 {
+		args = append(args, []string{"-b", strings.Join(cookies, "; ")})
+	}
+
+	settings := req.Settings()
+	if settings.ConnectTimeout != nil {
+		args = append(args, []string{"--connect-timeout", seconds(settings.ConnectTimeout.Duration)})
+	}
+	if settings.MaxTime != nil {
+		args = append(args, []string{"--max-time", seconds(settings.MaxTime.Duration)})
+	}
+	if settings.Follow != nil && *settings.Follow {
+		if settings.LocationTrusted != nil && *settings.LocationTrusted {
+			args = append(args, []string{"--location-trusted"})
+		} else {
+			args = append(args, []string{"-L"})
+		}
+		if settings.MaxRedirects != nil {
+			args = append(args, []string{"--max-redirs", strconv.Itoa(*settings.MaxRedirects)})
+		}
+	}
+
+	if settings.Insecure != nil && *settings.Insecure {
+		args = append(args, []string{"-k"})
+	}
+	if settings.CACert != "" {
+		args = append(args, []string{"--cacert", settings.CACert})
+	}
+	if settings.CAPath != "" {
+		args = append(args, []string{"--capath", settings.CAPath})
+	}
+	if settings.Cert != "" {
+		args = append(args, []string{"--cert", settings.Cert})
+	}
+	if settings.Key != "" {
+		args = append(args, []string{"--key", settings.Key})
+	}
+	if settings.CertPassword != "" {
+		args = append(args, []string{"--pass", settings.CertPassword})
+	}
+	if settings.TLSMin != "" {
+		args = append(args, []string{"--tlsv" + tlsVersion(settings.TLSMin)})
+	}
+	if settings.TLSMax != "" {
+		args = append(args, []string{"--tls-max", tlsVersion(settings.TLSMax)})
+	}
+	if settings.Retry != nil {
+		args = append(args, []string{"--retry", strconv.Itoa(*settings.Retry)})
+	}
+	if settings.RetryDelay != nil {
+		args = append(args, []string{"--retry-delay", seconds(settings.RetryDelay.Duration)})
+	}
+	if settings.RetryMaxTime != nil {
+		args = append(args, []string{"--retry-max-time", seconds(settings.RetryMaxTime.Duration)})
+	}
+	// curl can't retry on specific status codes, only on everything
+	if settings.RetryOn == RetryAllErrors {
+		args = append(args, []string{"--retry-all-errors"})
+	}
+	if settings.Compressed != nil && *settings.Compressed {
+		args = append(args, []string{"--compressed"})
+	}
+	if settings.HTTPVersion != "" {
+		args = append(args, []string{"--http" + settings.HTTPVersion})
+	}
+	if settings.Proxy != "" {
+		args = append(args, []string{"-x", settings.Proxy})
+	}
+	if settings.NoProxy != "" {
+		args = append(args, []string{"--noproxy", settings.NoProxy})
+	}
+	for _, r := range settings.Resolve {
+		args = append(args, []string{"--resolve", r})
+	}
+	for _, r := range settings.ConnectTo {
+		args = append(args, []string{"--connect-to", r})
+	}
+	if settings.DNSServers != "" {
+		args = append(args, []string{"--dns-servers", settings.DNSServers})
+	}
+	if settings.IPVersion != "" {
+		args = append(args, []string{"--ipv" + settings.IPVersion})
+	}
+	if abstract, ok := strings.CutPrefix(settings.UnixSocket, "@"); ok {
+		args = append(args, []string{"--abstract-unix-socket", abstract})
+	} else if settings.UnixSocket != "" {
+		args = append(args, []string{"--unix-socket", settings.UnixSocket})
+	}
+
+	pipe := ""
+	if len(body) != 0 {
+		if strings.IndexByte(string(body), 0) != -1 {
+			// arguments can't hold NUL bytes, so the body goes through stdin
+			pipe = "printf '%b' " + quotePrintf(body) + " | "
+			args = append(args, []string{"--data-binary", "@-"})
+		} else {
+			args = append(args, []string{"--data-raw", string(body)})
+		}
+	}
+
+	buf.WriteString(pipe)
+	buf.WriteString("curl")
+	for _, arg := range args {
+		buf.WriteString(" \\\n  ")
+		for i, a := range arg {
+			if i > 0 {
+				buf.WriteByte(' ')
+			}
+			buf.WriteString(Quote(a))
+		}
+	}
+	buf.WriteString(" \\\n  ")
+	buf.WriteString(Quote(r.URL.String()))
+
+	return buf.String(), nil
+}
+
+// tlsVersion turns "TLSv1.2" or "tls1.2" into the "1.2" curl expects.
+func tlsVersion(v string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(v), "tls"), "v")
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// Quote makes s safe to paste into a POSIX shell as a single argument.
+func Quote(s string) string {
+	if s == "" {
+		return "''"
+	}
+
+	if strings.IndexFunc(s, isUnsafe) == -1 {
+		return s
+	}
+
+	if utf8.ValidString(s) && strings.IndexFunc(s, isControl) == -1 {
+		return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	}
+
+	var b strings.Builder
+	b.WriteString("$'")
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			fmt.Fprintf(&b, `\x%02x`, s[i])
+		case r == '\\' || r == '\'':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case isControl(r):
+			fmt.Fprintf(&b, `\x%02x`, r)
+		default:
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	b.WriteByte('\'')
+
+	return b.String()
+}
+
+// quotePrintf escapes arbitrary bytes for printf '%b', which handles NUL.
+func quotePrintf(data []byte) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	for _, c := range data {
+		switch {
+		case c == '\'':
+			b.WriteString(`'\''`)
+		case c == '\\':
+			b.WriteString(`\\`)
+		case c >= 0x20 && c < 0x7f:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, `\0%03o`, c)
+		}
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+func isUnsafe(r rune) bool {
+	if r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+		return false
+	}
+	return !strings.ContainsRune("@%+=:,./-_", r)
+}
+
+func isControl(r rune) bool {
+	return unicode.IsControl(r) && r != '\t'
+}
@@ -0,0 +1,689 @@
+package curl
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/bigelle/ghostman/internal/httpcore"
+)
+
+type option struct {
+	long     string
+	short    byte
+	hasValue bool
+}
+
+var options = []option{
+	{long: "request", short: 'X', hasValue: true},
+	{long: "header", short: 'H', hasValue: true},
+	{long: "data", short: 'd', hasValue: true},
+	{long: "data-ascii", hasValue: true},
+	{long: "data-raw", hasValue: true},
+	{long: "data-binary", hasValue: true},
+	{long: "data-urlencode", hasValue: true},
+	{long: "json", hasValue: true},
+	{long: "form", short: 'F', hasValue: true},
+	{long: "form-string", hasValue: true},
+	{long: "cookie", short: 'b', hasValue: true},
+	{long: "user", short: 'u', hasValue: true},
+	{long: "user-agent", short: 'A', hasValue: true},
+	{long: "referer", short: 'e', hasValue: true},
+	{long: "url", hasValue: true},
+	{long: "get", short: 'G'},
+	{long: "head", short: 'I'},
+	{long: "compressed"},
+	{long: "insecure", short: 'k'},
+	{long: "location", short: 'L'},
+	{long: "location-trusted"},
+	{long: "max-redirs", hasValue: true},
+	{long: "cacert", hasValue: true},
+	{long: "capath", hasValue: true},
+	{long: "cert", short: 'E', hasValue: true},
+	{long: "key", hasValue: true},
+	{long: "pass", hasValue: true},
+	{long: "tlsv1"},
+	{long: "tlsv1.0"},
+	{long: "tlsv1.1"},
+	{long: "tlsv1.2"},
+	{long: "tlsv1.3"},
+	{long: "tls-max", hasValue: true},
+	{long: "max-time", short: 'm', hasValue: true},
+	{long: "connect-timeout", hasValue: true},
+	{long: "proxy", short: 'x', hasValue: true},
+	{long: "proxy-user", short: 'U', hasValue: true},
+	{long: "noproxy", hasValue: true},
+	{long: "socks5", hasValue: true},
+	{long: "socks5-hostname", hasValue: true},
+	{long: "retry", hasValue: true},
+	{long: "retry-delay", hasValue: true},
+	{long: "retry-max-time", hasValue: true},
+	{long: "retry-all-errors"},
+	{long: "retry-connrefused"},
+	{long: "http1.1"},
+	{long: "http2"},
+	{long: "http2-prior-knowledge"},
+	{long: "resolve", hasValue: true},
+	{long: "connect-to", hasValue: true},
+	{long: "dns-servers", hasValue: true},
+	{long: "ipv4", short: '4'},
+	{long: "ipv6", short: '6'},
+	{long: "unix-socket", hasValue: true},
+	{long: "abstract-unix-socket", hasValue: true},
+
+	// options that change nothing about the request itself
+	{long: "silent", short: 's'},
+	{long: "show-error", short: 'S'},
+	{long: "verbose", short: 'v'},
+	{long: "include", short: 'i'},
+	{long: "fail", short: 'f'},
+	{long: "no-progress-meter"},
+	{long: "progress-bar", short: '#'},
+	{long: "output", short: 'o', hasValue: true},
+	{long: "remote-name", short: 'O'},
+	{long: "write-out", short: 'w', hasValue: true},
+	{long: "dump-header", short: 'D', hasValue: true},
+	{long: "output-dir", hasValue: true},
+	{long: "stderr", hasValue: true},
+	{long: "trace", hasValue: true},
+	{long: "trace-ascii", hasValue: true},
+	{long: "trace-config", hasValue: true},
+	{long: "limit-rate", hasValue: true},
+	{long: "max-filesize", hasValue: true},
+	{long: "speed-limit", short: 'Y', hasValue: true},
+	{long: "speed-time", short: 'y', hasValue: true},
+	{long: "keepalive-time", hasValue: true},
+	{long: "expect100-timeout", hasValue: true},
+
+	// options that take a value but aren't supported yet. they are listed
+	// so that their value isn't mistaken for the URL
+	{long: "cookie-jar", short: 'c', hasValue: true},
+	{long: "cert-type", hasValue: true},
+	{long: "key-type", hasValue: true},
+	{long: "ciphers", hasValue: true},
+	{long: "tls13-ciphers", hasValue: true},
+	{long: "pinnedpubkey", hasValue: true},
+	{long: "crlfile", hasValue: true},
+	{long: "range", short: 'r', hasValue: true},
+	{long: "time-cond", short: 'z', hasValue: true},
+	{long: "etag-compare", hasValue: true},
+	{long: "etag-save", hasValue: true},
+	{long: "oauth2-bearer", hasValue: true},
+	{long: "aws-sigv4", hasValue: true},
+	{long: "netrc-file", hasValue: true},
+	{long: "config", short: 'K', hasValue: true},
+	{long: "interface", hasValue: true},
+	{long: "local-port", hasValue: true},
+	{long: "proto", hasValue: true},
+	{long: "proto-redir", hasValue: true},
+	{long: "proxy-header", hasValue: true},
+	{long: "request-target", hasValue: true},
+	{long: "happy-eyeballs-timeout-ms", hasValue: true},
+}
+
+// RetryAllErrors is what --retry-all-errors retries.
+const RetryAllErrors = "connect,4xx,5xx"
+
+var silent = map[string]bool{
+	"silent": true, "show-error": true, "verbose": true, "include": true, "fail": true,
+	"no-progress-meter": true, "progress-bar": true, "output": true, "remote-name": true,
+	"write-out": true, "dump-header": true, "output-dir": true, "stderr": true, "trace": true,
+	"trace-ascii": true, "trace-config": true, "limit-rate": true, "max-filesize": true,
+	"speed-limit": true, "speed-time": true, "keepalive-time": true, "expect100-timeout": true,
+}
+
+func lookupLong(name string) (option, bool) {
+	for _, o := range options {
+		if o.long == name {
+			return o, true
+		}
+	}
+	return option{}, false
+}
+
+func lookupShort(c byte) (option, bool) {
+	for _, o := range options {
+		if o.short != 0 && o.short == c {
+			return o, true
+		}
+	}
+	return option{}, false
+}
+
+type parser struct {
+	method   string
+	rawURL   string
+	headers  [][2]string
+	cookies  []httpcore.Cookie
+	data     []string
+	dataFile string
+	parts    []httpcore.MultipartField
+	get      bool
+	head     bool
+	isJSON   bool
+	// credentials from --proxy-user, added to the proxy URL when building
+	proxyUser string
+	settings  *httpcore.Settings
+	warnings  []string
+}
+
+// Parse turns the arguments of a curl invocation into a request. Options
+// that can't be represented are reported as warnings instead of failing.
+func Parse(args []string) (ser httpcore.RequestSerializable, warnings []string, err error) {
+	if len(args) > 0 && args[0] == "curl" {
+		args = args[1:]
+	}
+
+	p := &parser{}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case arg == "--":
+			for _, rest := range args[i+1:] {
+				p.setURL(rest)
+			}
+			i = len(args)
+
+		case strings.HasPrefix(arg, "--"):
+			name, val, hasVal := strings.Cut(arg[2:], "=")
+			opt, ok := lookupLong(name)
+			if !ok {
+				p.warn("unsupported option --%s ignored", name)
+				continue
+			}
+			if opt.hasValue && !hasVal {
+				if i+1 >= len(args) {
+					return ser, nil, fmt.Errorf("option --%s requires a value", name)
+				}
+				i++
+				val = args[i]
+			}
+			if err = p.apply(opt, val); err != nil {
+				return ser, nil, err
+			}
+
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			for j := 1; j < len(arg); j++ {
+				opt, ok := lookupShort(arg[j])
+				if !ok {
+					p.warn("unsupported option -%c ignored", arg[j])
+					continue
+				}
+				if !opt.hasValue {
+					if err = p.apply(opt, ""); err != nil {
+						return ser, nil, err
+					}
+					continue
+				}
+
+				val := arg[j+1:]
+				if val == "" {
+					if i+1 >= len(args) {
+						return ser, nil, fmt.Errorf("option -%c requires a value", arg[j])
+					}
+					i++
+					val = args[i]
+				}
+				if err = p.apply(opt, val); err != nil {
+					return ser, nil, err
+				}
+				break
+			}
+
+		default:
+			p.setURL(arg)
+		}
+	}
+
+	ser, err = p.build()
+	return ser, p.warnings, err
+}
+
+func (p *parser) warn(format string, args ...any) {
+	p.warnings = append(p.warnings, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) ensureSettings() *httpcore.Settings {
+	if p.settings == nil {
+		p.settings = &httpcore.Settings{}
+	}
+	return p.settings
+}
+
+func (p *parser) setURL(u string) {
+	switch {
+	case p.rawURL == "":
+		p.rawURL = u
+	case !looksLikeURL(p.rawURL) && looksLikeURL(u):
+		// most likely the value of an option the parser doesn't know
+		p.warn("%s doesn't look like a URL and was ignored", p.rawURL)
+		p.rawURL = u
+	default:
+		p.warn("only one URL is supported, %s ignored", u)
+	}
+}
+
+// looksLikeURL tells URLs from stray option values like "100k": a URL has
+// a scheme, or a host with a dot, an IPv6 address or localhost.
+func looksLikeURL(s string) bool {
+	if strings.Contains(s, "://") {
+		return true
+	}
+	host, _, _ := strings.Cut(s, "/")
+	host, _, _ = strings.Cut(host, "?")
+	if strings.HasPrefix(host, "[") {
+		return true
+	}
+	host, _, _ = strings.Cut(host, ":")
+	return strings.Contains(host, ".") || strings.EqualFold(host, "localhost")
+}
+
+func (p *parser) apply(opt option, val string) error {
+	if silent[opt.long] {
+		return nil
+	}
+
+	switch opt.long {
+	case "request":
+		p.method = strings.ToUpper(val)
+	case "header":
+		return p.addHeader(val)
+	case "user-agent":
+		p.headers = append(p.headers, [2]string{"User-Agent", val})
+	case "referer":
+		p.headers = append(p.headers, [2]string{"Referer", val})
+	case "url":
+		p.setURL(val)
+	case "get":
+		p.get = true
+	case "head":
+		p.head = true
+	case "user":
+		user := val
+		if !strings.Contains(user, ":") {
+			p.warn("no password given for user %s, using an empty one", user)
+			user += ":"
+		}
+		p.headers = append(p.headers, [2]string{
+			"Authorization", "Basic " + base64.StdEncoding.EncodeToString([]byte(user)),
+		})
+	case "cookie":
+		return p.addCookies(val)
+	case "data", "data-ascii", "data-binary":
+		if strings.HasPrefix(val, "@") {
+			return p.addDataFile(val[1:])
+		}
+		p.data = append(p.data, val)
+	case "data-raw":
+		p.data = append(p.data, val)
+	case "json":
+		p.isJSON = true
+		if strings.HasPrefix(val, "@") {
+			return p.addDataFile(val[1:])
+		}
+		p.data = append(p.data, val)
+	case "data-urlencode":
+		return p.addURLEncoded(val)
+	case "form":
+		return p.addPart(val, false)
+	case "form-string":
+		return p.addPart(val, true)
+	case "max-time", "connect-timeout":
+		d, err := httpcore.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("--%s: %w", opt.long, err)
+		}
+		if opt.long == "max-time" {
+			p.ensureSettings().MaxTime = &d
+		} else {
+			p.ensureSettings().ConnectTimeout = &d
+		}
+	case "location", "location-trusted":
+		follow := true
+		p.ensureSettings().Follow = &follow
+		if opt.long == "location-trusted" {
+			p.settings.LocationTrusted = &follow
+		}
+	case "insecure":
+		insecure := true
+		p.ensureSettings().Insecure = &insecure
+	case "compressed":
+		compressed := true
+		p.ensureSettings().Compressed = &compressed
+	case "cacert":
+		p.ensureSettings().CACert = val
+	case "capath":
+		p.ensureSettings().CAPath = val
+	case "cert":
+		// curl takes the password after a colon, except for a drive letter
+		file, pass, found := strings.Cut(val, ":")
+		if found && len(file) > 1 {
+			p.ensureSettings().Cert, p.settings.CertPassword = file, pass
+		} else {
+			p.ensureSettings().Cert = val
+		}
+	case "key":
+		p.ensureSettings().Key = val
+	case "pass":
+		p.ensureSettings().CertPassword = val
+	case "tlsv1", "tlsv1.0", "tlsv1.1", "tlsv1.2", "tlsv1.3":
+		version := strings.TrimPrefix(opt.long, "tlsv")
+		if version == "1" {
+			version = "1.0"
+		}
+		p.ensureSettings().TLSMin = version
+	case "tls-max":
+		if _, err := httpcore.ParseTLSVersion(val); err != nil {
+			return fmt.Errorf("--tls-max: %w", err)
+		}
+		p.ensureSettings().TLSMax = val
+	case "retry":
+		n, err := strconv.Atoi(val)
+		if err != nil || n < 0 {
+			return fmt.Errorf("--retry: invalid number %q", val)
+		}
+		p.ensureSettings().Retry = &n
+	case "retry-delay", "retry-max-time":
+		d, err := httpcore.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("--%s: %w", opt.long, err)
+		}
+		if opt.long == "retry-delay" {
+			p.ensureSettings().RetryDelay = &d
+		} else {
+			p.ensureSettings().RetryMaxTime = &d
+		}
+	case "retry-all-errors":
+		p.ensureSettings().RetryOn = RetryAllErrors
+	case "retry-connrefused":
+		// refused connections are retried by default
+	case "http1.1":
+		p.ensureSettings().HTTPVersion = httpcore.HTTP11
+	case "http2":
+		p.ensureSettings().HTTPVersion = httpcore.HTTP2
+	case "http2-prior-knowledge":
+		p.ensureSettings().HTTPVersion = httpcore.HTTP2PriorKnowledge
+	case "resolve":
+		if _, err := httpcore.ParseResolve(val); err != nil {
+			return fmt.Errorf("--resolve: %w", err)
+		}
+		s := p.ensureSettings()
+		s.Resolve = append(s.Resolve, val)
+	case "connect-to":
+		if _, err := httpcore.ParseConnectTo(val); err != nil {
+			return fmt.Errorf("--connect-to: %w", err)
+		}
+		s := p.ensureSettings()
+		s.ConnectTo = append(s.ConnectTo, val)
+	case "dns-servers":
+		p.ensureSettings().DNSServers = val
+	case "ipv4":
+		p.ensureSettings().IPVersion = "4"
+	case "ipv6":
+		p.ensureSettings().IPVersion = "6"
+	case "unix-socket":
+		p.ensureSettings().UnixSocket = val
+	case "abstract-unix-socket":
+		// Go names abstract sockets with a leading @
+		p.ensureSettings().UnixSocket = "@" + val
+	case "proxy":
+		if _, err := httpcore.ParseProxyURL(val); err != nil {
+			return fmt.Errorf("--proxy: %w", err)
+		}
+		p.ensureSettings().Proxy = val
+	case "socks5", "socks5-hostname":
+		p.ensureSettings().Proxy = "socks5://" + val
+	case "proxy-user":
+		p.proxyUser = val
+	case "noproxy":
+		p.ensureSettings().NoProxy = val
+	case "max-redirs":
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			return fmt.Errorf("--max-redirs: invalid number %q", val)
+		}
+		if n < 0 {
+			// curl takes -1 as unlimited
+			n = math.MaxInt32
+		}
+		p.ensureSettings().MaxRedirects = &n
+	default:
+		p.warn("option --%s is not supported yet and was ignored", opt.long)
+	}
+
+	return nil
+}
+
+func (p *parser) addHeader(val string) error {
+	name, value, ok := strings.Cut(val, ":")
+	if !ok {
+		// curl sends "Name;" as a header with an empty value
+		if strings.HasSuffix(val, ";") {
+			p.headers = append(p.headers, [2]string{strings.TrimSuffix(val, ";"), ""})
+			return nil
+		}
+		return fmt.Errorf("malformed header: %s", val)
+	}
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		p.warn("header %s removes a curl default and was ignored", name)
+		return nil
+	}
+
+	p.headers = append(p.headers, [2]string{strings.TrimSpace(name), value})
+	return nil
+}
+
+func (p *parser) addCookies(val string) error {
+	if !strings.Contains(val, "=") {
+		p.warn("cookies from file %s ignored, pass it with --jar when sending", val)
+		return nil
+	}
+
+	for _, pair := range strings.Split(val, ";") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("malformed cookie: %s", pair)
+		}
+		p.cookies = append(p.cookies, httpcore.Cookie{Name: name, Value: value})
+	}
+	return nil
+}
+
+func (p *parser) addDataFile(path string) error {
+	if path == "-" {
+		return fmt.Errorf("reading data from stdin is not supported")
+	}
+	if p.dataFile != "" {
+		return fmt.Errorf("only one data file is supported")
+	}
+	p.dataFile = path
+	return nil
+}
+
+func (p *parser) addURLEncoded(val string) error {
+	name, content, hasName := strings.Cut(val, "=")
+	if !hasName {
+		if n, file, isFile := strings.Cut(val, "@"); isFile {
+			p.warn("--data-urlencode %s@%s reads a file, which is not supported, ignored", n, file)
+			return nil
+		}
+		p.data = append(p.data, url.QueryEscape(val))
+		return nil
+	}
+
+	if name == "" {
+		p.data = append(p.data, url.QueryEscape(content))
+		return nil
+	}
+	p.data = append(p.data, name+"="+url.QueryEscape(content))
+	return nil
+}
+
+func (p *parser) addPart(val string, literal bool) error {
+	name, value, ok := strings.Cut(val, "=")
+	if !ok {
+		return fmt.Errorf("malformed form field: %s", val)
+	}
+
+	if literal {
+		p.parts = append(p.parts, httpcore.MultipartField{Name: name, Text: value})
+		return nil
+	}
+
+	switch {
+	case strings.HasPrefix(value, "@"):
+		file, params, _ := strings.Cut(value[1:], ";")
+		if params != "" {
+			p.warn("form field %s: parameters %q ignored", name, params)
+		}
+		p.parts = append(p.parts, httpcore.MultipartField{Name: name, File: file})
+	case strings.HasPrefix(value, "<"):
+		p.warn("form field %s: reading text content from a file is not supported, ignored", name)
+	default:
+		p.parts = append(p.parts, httpcore.MultipartField{Name: name, Text: value})
+	}
+	return nil
+}
+
+// applyProxyUser puts the --proxy-user credentials into the proxy URL, which
+// may come before or after them on the command line.
+func (p *parser) applyProxyUser() error {
+	if p.proxyUser == "" {
+		return nil
+	}
+	if p.settings == nil || p.settings.Proxy == "" {
+		p.warn("--proxy-user without --proxy ignored")
+		return nil
+	}
+
+	u, err := httpcore.ParseProxyURL(p.settings.Proxy)
+	if err != nil {
+		return fmt.Errorf("--proxy: %w", err)
+	}
+	user, pass, hasPass := strings.Cut(p.proxyUser, ":")
+	if hasPass {
+		u.User = url.UserPassword(user, pass)
+	} else {
+		u.User = url.User(user)
+	}
+	p.settings.Proxy = u.String()
+
+	return nil
+}
+
+func (p *parser) build() (ser httpcore.RequestSerializable, err error) {
+	if p.rawURL == "" {
+		return ser, fmt.Errorf("no URL given")
+	}
+
+	rawURL := p.rawURL
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ser, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	query := u.Query()
+	u.RawQuery = ""
+
+	if err = p.applyProxyUser(); err != nil {
+		return ser, err
+	}
+
+	ser = httpcore.RequestSerializable{
+		URL:      u.String(),
+		Headers:  make(map[string][]string),
+		Cookies:  p.cookies,
+		Settings: p.settings,
+	}
+
+	for _, h := range p.headers {
+		ser.Headers[h[0]] = append(ser.Headers[h[0]], h[1])
+	}
+
+	hasData := len(p.data) != 0 || p.dataFile != ""
+	if hasData && len(p.parts) != 0 {
+		return ser, fmt.Errorf("can't combine data and form fields in one request")
+	}
+	if p.dataFile != "" && len(p.data) != 0 {
+		return ser, fmt.Errorf("can't combine a data file with other data")
+	}
+
+	switch {
+	case hasData && p.get:
+		if p.dataFile != "" {
+			return ser, fmt.Errorf("can't send a data file as query parameters")
+		}
+		extra, err := url.ParseQuery(strings.Join(p.data, "&"))
+		if err != nil {
+			return ser, fmt.Errorf("parsing data as query: %w", err)
+		}
+		for k, vals := range extra {
+			query[k] = append(query[k], vals...)
+		}
+
+	case hasData:
+		body := &httpcore.BodySpec{Type: "content"}
+		if p.dataFile != "" {
+			body.File = &p.dataFile
+		} else {
+			text := strings.Join(p.data, "&")
+			body.Text = &text
+		}
+		ser.Body = body
+
+		if p.isJSON {
+			setDefaultHeader(ser.Headers, "Content-Type", "application/json")
+			setDefaultHeader(ser.Headers, "Accept", "application/json")
+		}
+		setDefaultHeader(ser.Headers, "Content-Type", "application/x-www-form-urlencoded")
+
+	case len(p.parts) != 0:
+		ser.Body = &httpcore.BodySpec{Type: "multipart", MultipartFields: &p.parts}
+	}
+
+	if len(query) != 0 {
+		ser.QueryParams = query
+	}
+
+	switch {
+	case p.method != "":
+		ser.Method = p.method
+	case p.head:
+		ser.Method = http.MethodHead
+	case ser.Body != nil:
+		ser.Method = http.MethodPost
+	default:
+		ser.Method = http.MethodGet
+	}
+
+	if len(ser.Headers) == 0 {
+		ser.Headers = nil
+	}
+
+	return ser, nil
+}
+
+func setDefaultHeader(h map[string][]string, key, val string) {
+	for k := range h {
+		if strings.EqualFold(k, key) {
+			return
+		}
+	}
+	h[key] = []string{val}
+}
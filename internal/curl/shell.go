@@ -0,0 +1,195 @@
+package curl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// SplitCommand splits a pasted command line into arguments the way a POSIX
+// shell would: single and double quotes, $'...' strings from "Copy as cURL",
+// backslash escapes and line continuations.
+func SplitCommand(s string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+
+		case c == '\\':
+			if i+1 >= len(s) {
+				return nil, fmt.Errorf("trailing backslash")
+			}
+			i++
+			// line continuation
+			if s[i] == '\n' {
+				continue
+			}
+			if s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n' {
+				i++
+				continue
+			}
+			cur.WriteByte(s[i])
+			inArg = true
+
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			cur.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inArg = true
+
+		case c == '$' && i+1 < len(s) && s[i+1] == '\'':
+			n, err := readANSIC(s[i+2:], &cur)
+			if err != nil {
+				return nil, err
+			}
+			i += n + 1
+			inArg = true
+
+		case c == '"':
+			n, err := readDoubleQuoted(s[i+1:], &cur)
+			if err != nil {
+				return nil, err
+			}
+			i += n
+			inArg = true
+
+		default:
+			cur.WriteByte(c)
+			inArg = true
+		}
+	}
+
+	if inArg {
+		args = append(args, cur.String())
+	}
+
+	return args, nil
+}
+
+// readDoubleQuoted consumes the string up to and including the closing
+// quote, returning how many bytes it read.
+func readDoubleQuoted(s string, out *strings.Builder) (int, error) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return i + 1, nil
+		case '\\':
+			if i+1 < len(s) {
+				switch s[i+1] {
+				case '"', '\\', '$', '`':
+					out.WriteByte(s[i+1])
+					i++
+					continue
+				case '\n':
+					i++
+					continue
+				}
+			}
+			out.WriteByte('\\')
+		default:
+			out.WriteByte(s[i])
+		}
+	}
+	return 0, fmt.Errorf("unterminated double quote")
+}
+
+// readANSIC consumes a $'...' string up to and including the closing quote,
+// returning how many bytes it read.
+func readANSIC(s string, out *strings.Builder) (int, error) {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\'' {
+			return i + 1, nil
+		}
+		if c != '\\' {
+			out.WriteByte(c)
+			continue
+		}
+
+		i++
+		if i >= len(s) {
+			break
+		}
+
+		switch s[i] {
+		case 'n':
+			out.WriteByte('\n')
+		case 't':
+			out.WriteByte('\t')
+		case 'r':
+			out.WriteByte('\r')
+		case 'a':
+			out.WriteByte('\a')
+		case 'b':
+			out.WriteByte('\b')
+		case 'e', 'E':
+			out.WriteByte(0x1b)
+		case 'f':
+			out.WriteByte('\f')
+		case 'v':
+			out.WriteByte('\v')
+		case 'x':
+			n := hexLen(s[i+1:], 2)
+			if n == 0 {
+				out.WriteString(`\x`)
+				continue
+			}
+			b, _ := strconv.ParseUint(s[i+1:i+1+n], 16, 8)
+			out.WriteByte(byte(b))
+			i += n
+		case 'u', 'U':
+			max := 4
+			if s[i] == 'U' {
+				max = 8
+			}
+			n := hexLen(s[i+1:], max)
+			if n == 0 {
+				out.WriteByte('\\')
+				out.WriteByte(s[i])
+				continue
+			}
+			r, _ := strconv.ParseUint(s[i+1:i+1+n], 16, 32)
+			out.WriteRune(rune(r))
+			i += n
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			n := 1
+			for n < 3 && i+n < len(s) && s[i+n] >= '0' && s[i+n] <= '7' {
+				n++
+			}
+			b, _ := strconv.ParseUint(s[i:i+n], 8, 8)
+			out.WriteByte(byte(b))
+			i += n - 1
+		default:
+			// covers \\, \' and \" as well as unknown escapes
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if r != '\\' && r != '\'' && r != '"' && r != '?' {
+				out.WriteByte('\\')
+			}
+			out.WriteString(s[i : i+size])
+			i += size - 1
+		}
+	}
+	return 0, fmt.Errorf("unterminated $'...' string")
+}
+
+func hexLen(s string, max int) int {
+	n := 0
+	for n < max && n < len(s) && strings.IndexByte("0123456789abcdefABCDEF", s[n]) != -1 {
+		n++
+	}
+	return n
+}
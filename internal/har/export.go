@@ -0,0 +1,233 @@
+package har
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bigelle/ghostman/internal/httpcore"
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// NewEntries records every request a response took, see
+// httpcore.Response.Hops: one entry per followed redirect and retried
+// attempt, with an empty body since those were discarded, and last the
+// final exchange.
+func NewEntries(resp *httpcore.Response) []Entry {
+	hops := resp.Hops()
+	entries := make([]Entry, len(hops))
+	for i, h := range hops {
+		var body []byte
+		size, wireSize := int64(0), int64(-1)
+		if i == len(hops)-1 {
+			body, size, wireSize = resp.Body(), resp.Size(), resp.WireSize()
+		}
+		entries[i] = newEntry(h, body, size, wireSize)
+	}
+	return entries
+}
+
+func newEntry(h httpcore.Hop, body []byte, size, wireSize int64) Entry {
+	entry := Entry{
+		StartedDateTime: h.Started.Format(time.RFC3339Nano),
+		Request:         newRequest(h.Request, h.Response.Proto, requestBody(h.Request)),
+		Response:        newResponse(h.Response, body, size, wireSize),
+		Timings: Timings{
+			Blocked: -1,
+			DNS:     -1,
+			Connect: -1,
+			SSL:     -1,
+		},
+	}
+
+	if h.Timing != nil {
+		entry.Timings = timings(*h.Timing)
+	}
+	entry.Time = entry.Timings.total()
+
+	return entry
+}
+
+// requestBody reads a copy of the body r was sent with. requests built by
+// httpcore can always replay their body.
+func requestBody(r *http.Request) []byte {
+	if r.Body == nil || r.Body == http.NoBody || r.GetBody == nil {
+		return nil
+	}
+	body, err := r.GetBody()
+	if err != nil {
+		return nil
+	}
+	defer body.Close()
+
+	data, _ := io.ReadAll(body)
+	return data
+}
+
+// timings maps the trace to HAR phases. HAR counts the TLS handshake as
+// part of connect, and uses -1 for phases a reused connection skipped.
+func timings(t httpcore.Timing) Timings {
+	tm := Timings{
+		Blocked: -1,
+		DNS:     -1,
+		Connect: -1,
+		SSL:     -1,
+		Send:    millis(t.Send),
+		Wait:    millis(t.Wait),
+		Receive: millis(t.Transfer),
+	}
+
+	if t.Reused {
+		return tm
+	}
+	if t.DNSLookup > 0 {
+		tm.DNS = millis(t.DNSLookup)
+	}
+	if t.TCPConnect > 0 {
+		tm.Connect = millis(t.TCPConnect + t.TLSHandshake)
+	}
+	if t.TLSHandshake > 0 {
+		tm.SSL = millis(t.TLSHandshake)
+	}
+	return tm
+}
+
+func newRequest(r *http.Request, proto string, body []byte) Request {
+	req := Request{
+		Method:      r.Method,
+		URL:         r.URL.String(),
+		HTTPVersion: proto,
+		Cookies:     cookies(r.Cookies()),
+		Headers:     headers(r.Header),
+		QueryString: []NameValue{},
+		HeadersSize: -1,
+		BodySize:    int64(len(body)),
+	}
+
+	for _, k := range sortedKeys(r.URL.Query()) {
+		for _, v := range r.URL.Query()[k] {
+			req.QueryString = append(req.QueryString, NameValue{Name: k, Value: v})
+		}
+	}
+
+	if len(body) != 0 {
+		req.PostData = &PostData{
+			MimeType: r.Header.Get("Content-Type"),
+			Text:     string(body),
+		}
+	}
+
+	return req
+}
+
+// newResponse takes the body as decoded by the client, its size and
+// wireSize, its size as received or -1 if unknown, which differ for
+// compressed bodies. a
+// streamed body is only its preview, but size still counts all of it; the
+// content comment then says the text was truncated.
+func newResponse(r *http.Response, body []byte, size, wireSize int64) Response {
+	resp := Response{
+		Status:      r.StatusCode,
+		StatusText:  http.StatusText(r.StatusCode),
+		HTTPVersion: r.Proto,
+		Cookies:     cookies(r.Cookies()),
+		Headers:     headers(r.Header),
+		RedirectURL: r.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    wireSize,
+	}
+
+	ct := r.Header.Get("Content-Type")
+	if ct == "" {
+		ct = mimetype.Detect(body).String()
+	}
+
+	resp.Content = Content{
+		Size:     size,
+		MimeType: ct,
+	}
+	if wireSize >= 0 {
+		resp.Content.Compression = size - wireSize
+	}
+
+	kept := body
+	truncated := int64(len(body)) < size
+	// the preview may end in the middle of a rune
+	if text := trimPartialRune(body); truncated && utf8.Valid(text) {
+		kept = text
+	}
+
+	if utf8.Valid(kept) {
+		resp.Content.Text = string(kept)
+	} else {
+		resp.Content.Text = base64.StdEncoding.EncodeToString(kept)
+		resp.Content.Encoding = "base64"
+	}
+	if truncated {
+		resp.Content.Comment = fmt.Sprintf("truncated to the first %d of %d bytes", len(kept), size)
+	}
+
+	return resp
+}
+
+// trimPartialRune drops an incomplete rune a cut left at the end of b.
+func trimPartialRune(b []byte) []byte {
+	for i := 1; i <= min(utf8.UTFMax, len(b)); i++ {
+		if utf8.RuneStart(b[len(b)-i]) {
+			if !utf8.FullRune(b[len(b)-i:]) {
+				return b[:len(b)-i]
+			}
+			break
+		}
+	}
+	return b
+}
+
+func cookies(cs []*http.Cookie) []Cookie {
+	out := make([]Cookie, 0, len(cs))
+	for _, c := range cs {
+		hc := Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Domain:   c.Domain,
+			HTTPOnly: c.HttpOnly,
+			Secure:   c.Secure,
+		}
+		if !c.Expires.IsZero() {
+			hc.Expires = c.Expires.Format(time.RFC3339)
+		}
+		out = append(out, hc)
+	}
+	return out
+}
+
+func headers(h http.Header) []NameValue {
+	out := make([]NameValue, 0, len(h))
+	for _, k := range sortedKeys(h) {
+		for _, v := range h[k] {
+			out = append(out, NameValue{Name: k, Value: v})
+		}
+	}
+	return out
+}
+
+func sortedKeys[M ~map[string][]string](m M) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	})
+	return keys
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,221 @@
+package har
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bigelle/ghostman/internal/collection"
+	"github.com/bigelle/ghostman/internal/httpcore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testHAR = `{
+  "log": {
+    "version": "1.2",
+    "creator": {"name": "WebInspector", "version": "537.36"},
+    "entries": [
+      {
+        "startedDateTime": "2025-01-01T00:00:00.000Z",
+        "time": 12.5,
+        "request": {
+          "method": "POST",
+          "url": "https://api.example.com/v1/users?debug=1",
+          "httpVersion": "h2",
+          "headers": [
+            {"name": ":authority", "value": "api.example.com"},
+            {"name": "content-type", "value": "application/json"},
+            {"name": "content-length", "value": "13"},
+            {"name": "cookie", "value": "sid=abc"}
+          ],
+          "queryString": [{"name": "debug", "value": "1"}],
+          "cookies": [{"name": "sid", "value": "abc"}],
+          "postData": {"mimeType": "application/json", "text": "{\"name\":\"a\"}"},
+          "headersSize": -1,
+          "bodySize": 13
+        },
+        "response": {
+          "status": 201, "statusText": "Created", "httpVersion": "h2",
+          "headers": [], "cookies": [],
+          "content": {"size": 0, "mimeType": "application/json"},
+          "redirectURL": "", "headersSize": -1, "bodySize": 0
+        },
+        "cache": {},
+        "timings": {"send": 1, "wait": 10, "receive": 1.5}
+      },
+      {
+        "startedDateTime": "2025-01-01T00:00:01.000Z",
+        "time": 3,
+        "request": {
+          "method": "POST",
+          "url": "https://api.example.com/login",
+          "httpVersion": "HTTP/1.1",
+          "headers": [],
+          "queryString": [],
+          "cookies": [],
+          "postData": {
+            "mimeType": "application/x-www-form-urlencoded",
+            "params": [{"name": "user", "value": "ghost"}]
+          },
+          "headersSize": -1,
+          "bodySize": 10
+        },
+        "response": {
+          "status": 302, "statusText": "Found", "httpVersion": "HTTP/1.1",
+          "headers": [], "cookies": [],
+          "content": {"size": 0, "mimeType": ""},
+          "redirectURL": "/", "headersSize": -1, "bodySize": 0
+        },
+        "cache": {},
+        "timings": {"send": 0, "wait": 3, "receive": 0}
+      }
+    ]
+  }
+}`
+
+func TestHAR_ToCollection(t *testing.T) {
+	h, err := Load(strings.NewReader(testHAR))
+	require.NoError(t, err)
+
+	c, warnings, err := h.ToCollection("capture")
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	assert.Equal(t, []string{"api.example.com/001-post-v1-users", "api.example.com/002-post-login"}, c.Paths())
+
+	ser, err := c.Resolve("api.example.com/001-post-v1-users")
+	require.NoError(t, err)
+
+	text := `{"name":"a"}`
+	assert.Equal(t, httpcore.RequestSerializable{
+		Method:      "POST",
+		URL:         "https://api.example.com/v1/users",
+		QueryParams: map[string][]string{"debug": {"1"}},
+		Headers:     map[string][]string{"content-type": {"application/json"}},
+		Cookies:     []httpcore.Cookie{{Name: "sid", Value: "abc"}},
+		Body:        &httpcore.BodySpec{Type: "content", Text: &text},
+	}, ser)
+
+	ser, err = c.Resolve("api.example.com/002-post-login")
+	require.NoError(t, err)
+	assert.Equal(t, &map[string][]string{"user": {"ghost"}}, ser.Body.FormData)
+}
+
+func TestHAR_ToCollection_NonHTTP(t *testing.T) {
+	var h HAR
+	for _, u := range []string{
+		"data:image/png;base64,iVBORw0KGgo=",
+		"blob:https://app.example.com/4b1d0c1e",
+		"chrome-extension://abcdef/script.js",
+		"https://app.example.com/api",
+	} {
+		h.Log.Entries = append(h.Log.Entries, Entry{Request: Request{Method: "GET", URL: u}})
+	}
+
+	c, warnings, err := h.ToCollection("capture")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"entry 1: data:image/png;base64,iVBORw0KGgo= is not an HTTP URL, skipped",
+		"entry 2: blob:https://app.example.com/4b1d0c1e is not an HTTP URL, skipped",
+		"entry 3: chrome-extension://abcdef/script.js is not an HTTP URL, skipped",
+	}, warnings)
+	assert.Equal(t, []string{"app.example.com/004-get-api"}, c.Paths())
+
+	// the written collection has to load again
+	var buf bytes.Buffer
+	require.NoError(t, c.Save(&buf))
+	_, err = collection.Load(&buf)
+	require.NoError(t, err)
+}
+
+func TestNewResponse_Truncated(t *testing.T) {
+	res := &http.Response{StatusCode: http.StatusOK, Proto: "HTTP/1.1", Header: http.Header{"Content-Type": {"text/plain"}}}
+
+	// the preview ends inside the two bytes of "é"
+	body := []byte("caf\xc3")
+	resp := newResponse(res, body, 100, 100)
+	assert.Equal(t, "caf", resp.Content.Text)
+	assert.Empty(t, resp.Content.Encoding)
+	assert.Equal(t, "truncated to the first 3 of 100 bytes", resp.Content.Comment)
+
+	binary := []byte{0xff, 0x00, 0xfe}
+	resp = newResponse(res, binary, 10, 10)
+	assert.Equal(t, "base64", resp.Content.Encoding)
+	assert.Equal(t, "truncated to the first 3 of 10 bytes", resp.Content.Comment)
+
+	resp = newResponse(res, []byte("café"), 5, 5)
+	assert.Equal(t, "café", resp.Content.Text)
+	assert.Empty(t, resp.Content.Comment)
+}
+
+func TestNewEntries(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			if hits.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			http.Redirect(w, r, "/c", http.StatusFound)
+		default:
+			fmt.Fprint(w, "done")
+		}
+	}))
+	defer srv.Close()
+
+	payload := "a=1"
+	req, err := httpcore.NewRequestFromSerializable(httpcore.RequestSerializable{
+		Method: http.MethodPost,
+		URL:    srv.URL + "/a",
+		Body:   &httpcore.BodySpec{Type: "content", Text: &payload},
+	})
+	require.NoError(t, err)
+
+	on, err := httpcore.ParseRetryOn("503")
+	require.NoError(t, err)
+	client := httpcore.NewClient(
+		httpcore.WithRedirects(5),
+		httpcore.WithRetry(httpcore.RetryPolicy{Retries: 1, Delay: time.Millisecond, On: on}),
+	)
+	resp, err := client.Send(context.Background(), req)
+	require.NoError(t, err)
+
+	entries := NewEntries(resp)
+	require.Len(t, entries, 3)
+
+	type hop struct {
+		Method, URL, Body string
+		Status            int
+	}
+	var hops []hop
+	for _, e := range entries {
+		h := hop{Method: e.Request.Method, URL: e.Request.URL, Status: e.Response.Status}
+		if e.Request.PostData != nil {
+			h.Body = e.Request.PostData.Text
+		}
+		hops = append(hops, h)
+
+		// HAR 1.2 wants time to be the sum of the timings
+		tm := e.Timings
+		assert.InDelta(t, max(tm.DNS, 0)+max(tm.Connect, 0)+tm.Send+tm.Wait+tm.Receive, e.Time, 1e-9)
+		assert.NotEmpty(t, e.StartedDateTime)
+	}
+	assert.Equal(t, []hop{
+		{Method: "POST", URL: srv.URL + "/a", Body: "a=1", Status: http.StatusServiceUnavailable},
+		{Method: "POST", URL: srv.URL + "/a", Body: "a=1", Status: http.StatusFound},
+		{Method: "GET", URL: srv.URL + "/c", Status: http.StatusOK},
+	}, hops)
+
+	assert.Equal(t, "/c", entries[1].Response.RedirectURL)
+	assert.Equal(t, int64(-1), entries[1].Response.BodySize)
+	assert.Equal(t, "done", entries[2].Response.Content.Text)
+	assert.Equal(t, int64(4), entries[2].Response.Content.Size)
+}
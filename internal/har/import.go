@@ -0,0 +1,160 @@
+package har
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/bigelle/ghostman/internal/collection"
+	"github.com/bigelle/ghostman/internal/httpcore"
+)
+
+// headers the client computes on its own, plus HTTP/2 pseudo-headers
+// which browsers put into captures
+var skipHeaders = map[string]bool{
+	"host":           true,
+	"content-length": true,
+	"cookie":         true,
+	"connection":     true,
+}
+
+// ToCollection turns every captured request into a collection entry, with
+// one folder per host. Responses are not imported.
+func (h HAR) ToCollection(name string) (*collection.Collection, []string, error) {
+	var warnings []string
+	c := &collection.Collection{Folder: collection.Folder{Name: name}}
+
+	folders := make(map[string]int)
+
+	for i, e := range h.Log.Entries {
+		u, err := url.Parse(e.Request.URL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("entry %d: invalid URL: %w", i+1, err)
+		}
+		// browsers capture data:, blob: and extension URLs as well
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			warnings = append(warnings, fmt.Sprintf("entry %d: %s is not an HTTP URL, skipped", i+1, shortURL(e.Request.URL)))
+			continue
+		}
+
+		ser, warns := e.Request.toSerializable(u)
+		reqName := requestName(i, e.Request.Method, u.Path)
+		for _, w := range warns {
+			warnings = append(warnings, fmt.Sprintf("%s/%s: %s", u.Host, reqName, w))
+		}
+
+		idx, ok := folders[u.Host]
+		if !ok {
+			idx = len(c.Folders)
+			folders[u.Host] = idx
+			c.Folders = append(c.Folders, collection.Folder{Name: u.Host})
+		}
+
+		c.Folders[idx].Requests = append(c.Folders[idx].Requests, collection.Request{
+			Name:                reqName,
+			RequestSerializable: ser,
+		})
+	}
+
+	return c, warnings, nil
+}
+
+// shortURL keeps warnings readable for data: URLs holding whole files.
+func shortURL(s string) string {
+	const max = 64
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "..."
+}
+
+func (r Request) toSerializable(u *url.URL) (ser httpcore.RequestSerializable, warnings []string) {
+	query := u.Query()
+	u.RawQuery = ""
+	u.Fragment = ""
+
+	ser = httpcore.RequestSerializable{
+		Method: r.Method,
+		URL:    u.String(),
+	}
+
+	if len(query) != 0 {
+		ser.QueryParams = query
+	}
+
+	for _, h := range r.Headers {
+		if strings.HasPrefix(h.Name, ":") || skipHeaders[strings.ToLower(h.Name)] {
+			continue
+		}
+		if ser.Headers == nil {
+			ser.Headers = make(map[string][]string)
+		}
+		ser.Headers[h.Name] = append(ser.Headers[h.Name], h.Value)
+	}
+
+	for _, c := range r.Cookies {
+		ser.Cookies = append(ser.Cookies, httpcore.Cookie{Name: c.Name, Value: c.Value})
+	}
+
+	if r.PostData == nil {
+		return ser, warnings
+	}
+
+	switch {
+	case r.PostData.Text != "":
+		text := r.PostData.Text
+		ser.Body = &httpcore.BodySpec{Type: "content", Text: &text}
+
+	case len(r.PostData.Params) != 0 && strings.HasPrefix(r.PostData.MimeType, "multipart/form-data"):
+		fields := make([]httpcore.MultipartField, 0, len(r.PostData.Params))
+		for _, p := range r.PostData.Params {
+			if p.FileName != "" {
+				warnings = append(warnings, fmt.Sprintf("file %s for field %s isn't part of the capture, set its path by hand", p.FileName, p.Name))
+				fields = append(fields, httpcore.MultipartField{Name: p.Name, File: p.FileName})
+				continue
+			}
+			fields = append(fields, httpcore.MultipartField{Name: p.Name, Text: p.Value})
+		}
+		ser.Body = &httpcore.BodySpec{Type: "multipart", MultipartFields: &fields}
+		// the boundary from the capture won't match the rebuilt body
+		dropHeader(ser.Headers, "Content-Type")
+
+	case len(r.PostData.Params) != 0:
+		form := make(map[string][]string)
+		for _, p := range r.PostData.Params {
+			form[p.Name] = append(form[p.Name], p.Value)
+		}
+		ser.Body = &httpcore.BodySpec{Type: "form", FormData: &form}
+	}
+
+	return ser, warnings
+}
+
+func dropHeader(h map[string][]string, key string) {
+	for k := range h {
+		if strings.EqualFold(k, key) {
+			delete(h, k)
+		}
+	}
+}
+
+func requestName(i int, method, path string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%03d-%s", i+1, strings.ToLower(method))
+
+	dash := true
+	for _, r := range path {
+		isWord := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '.'
+		if !isWord {
+			dash = true
+			continue
+		}
+		if dash {
+			b.WriteByte('-')
+			dash = false
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,40 @@
+package httpcore
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+)
+
+// AuthSpec describes credentials that end up in the Authorization header.
+// It's kept apart from headers so that {{variables}} can be resolved before
+// anything gets encoded.
+type AuthSpec struct {
+	Type     string `json:"type"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Token    string `json:"token,omitempty"`
+}
+
+// apply sets the Authorization header unless the request already has one.
+func (a AuthSpec) apply(req *http.Request) error {
+	if req.Header.Get("Authorization") != "" {
+		return nil
+	}
+
+	switch a.Type {
+	case "none":
+	case "basic":
+		creds := base64.StdEncoding.EncodeToString([]byte(a.Username + ":" + a.Password))
+		req.Header.Set("Authorization", "Basic "+creds)
+	case "bearer":
+		if a.Token == "" {
+			return fmt.Errorf("no token for bearer auth")
+		}
+		req.Header.Set("Authorization", "Bearer "+a.Token)
+	default:
+		return fmt.Errorf("unknown auth type: %s", a.Type)
+	}
+
+	return nil
+}
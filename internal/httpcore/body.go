@@ -8,6 +8,7 @@ import (
 	"net/textproto"
 	"net/url"
 	"os"
+	"strings"
 	"sync"
 
 	"github.com/bigelle/ghostman/internal/shared"
@@ -22,26 +23,29 @@ type BodySpec struct {
 	MultipartFields *[]MultipartField    `json:"multipart_fields"`
 }
 
-func (h BodySpec) Parse() ([]byte, error) {
+// Parse builds the body and returns the Content-Type it has to be sent
+// with, empty for content bodies, whose type is up to the request.
+func (h BodySpec) Parse() (buf []byte, ct string, err error) {
 	switch h.Type {
 	case "form":
 		if h.FormData == nil {
-			return nil, fmt.Errorf("empty form")
+			return nil, "", fmt.Errorf("empty form")
 		}
 		b := FormBytes(*h.FormData)
-		return b, nil
+		return b, "application/x-www-form-urlencoded", nil
 	case "multipart":
 		if h.MultipartFields == nil {
-			return nil, fmt.Errorf("no multipart fields")
+			return nil, "", fmt.Errorf("no multipart fields")
 		}
 		return h.toMultipart()
 	case "content":
-		if h.Text == nil {
-			return nil, fmt.Errorf("no content")
+		if h.Text == nil && h.File == nil {
+			return nil, "", fmt.Errorf("no content")
 		}
-		return h.toGeneric()
+		buf, err = h.toGeneric()
+		return buf, "", err
 	default:
-		return nil, fmt.Errorf("unknown body type: %s", h.Type)
+		return nil, "", fmt.Errorf("unknown body type: %s", h.Type)
 	}
 }
 
@@ -71,9 +75,10 @@ func (h BodySpec) toGeneric() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-func (h BodySpec) toMultipart() (buf []byte, err error) {
+// toMultipart builds the body and its Content-Type with the boundary in it.
+func (h BodySpec) toMultipart() (buf []byte, ct string, err error) {
 	if h.MultipartFields != nil && len(*h.MultipartFields) == 0 {
-		return nil, fmt.Errorf("no multipart fields")
+		return nil, "", fmt.Errorf("no multipart fields")
 	}
 
 	builder := NewMultipartBuilder()
@@ -82,27 +87,27 @@ func (h BodySpec) toMultipart() (buf []byte, err error) {
 		if part.Text != "" {
 			err = builder.AddTextField(part.Name, part.Text)
 			if err != nil {
-				return nil, fmt.Errorf("writing text part: %w", err)
+				return nil, "", fmt.Errorf("writing text part: %w", err)
 			}
 		} else if part.File != "" {
 			var f *os.File
 			f, err = os.Open(part.File)
 			if err != nil {
-				return nil, fmt.Errorf("opening file for reading: %w", err)
+				return nil, "", fmt.Errorf("opening file for reading: %w", err)
 			}
 			err = builder.AddFileReader(part.Name, f.Name(), f)
 			if err != nil {
-				return nil, fmt.Errorf("adding file to multipart: %w", err)
+				return nil, "", fmt.Errorf("adding file to multipart: %w", err)
 			}
 		}
 	}
 
 	buf, err = builder.Build()
 	if err != nil {
-		return nil, fmt.Errorf("closing multipart builder: %w", err)
+		return nil, "", fmt.Errorf("closing multipart builder: %w", err)
 	}
 
-	return buf, nil
+	return buf, "multipart/form-data; boundary=" + builder.Boundary(), nil
 }
 
 type MultipartField struct {
@@ -158,11 +163,7 @@ func (mb *MultipartBuilder) AddFile(field, file string, content []byte) (err err
 
 	ct := mimetype.Detect(content)
 	header := textproto.MIMEHeader{
-		"Content-Disposition": []string{
-			"form-data",
-			fmt.Sprintf(`name="%s"`, field),
-			fmt.Sprintf(`filename="%s"`, file),
-		},
+		"Content-Disposition": []string{fileDisposition(field, file)},
 		"Content-Type": []string{
 			ct.String(),
 		},
@@ -182,6 +183,14 @@ func (mb *MultipartBuilder) AddFile(field, file string, content []byte) (err err
 	return nil
 }
 
+// fileDisposition is the Content-Disposition of a file part. it has to be a
+// single value, separate ones are separate header lines that parsers drop.
+func fileDisposition(field, file string) string {
+	return fmt.Sprintf(`form-data; name="%s"; filename="%s"`, quoteEscaper.Replace(field), quoteEscaper.Replace(file))
+}
+
+var quoteEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
 // It IS going to drain your reader
 func (mb *MultipartBuilder) AddFileReader(field, file string, r io.Reader) (err error) {
 	mb.mu.Lock()
@@ -199,11 +208,7 @@ func (mb *MultipartBuilder) AddFileReader(field, file string, r io.Reader) (err
 	ct := mimetype.Detect(buf)
 
 	header := textproto.MIMEHeader{
-		"Content-Disposition": []string{
-			"form-data",
-			fmt.Sprintf(`name="%s"`, field),
-			fmt.Sprintf(`filename="%s"`, file),
-		},
+		"Content-Disposition": []string{fileDisposition(field, file)},
 		"Content-Type": []string{
 			ct.String(),
 		},
@@ -1,28 +1,55 @@
 package httpcore
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 )
 
-var (
-	dialer *net.Dialer = &net.Dialer{
-		Timeout:   8 * time.Second,
+const (
+	DefaultConnectTimeout        = 8 * time.Second
+	DefaultTLSHandshakeTimeout   = 8 * time.Second
+	DefaultResponseHeaderTimeout = 15 * time.Second
+)
+
+// Client owns its dialer, transport and http.Client, so options given to one
+// client never affect another. it's safe for concurrent use once created.
+type Client struct {
+	client    *http.Client
+	transport *http.Transport
+	dialer    *net.Dialer
+	dial      dialRules
+
+	maxRedirects    int
+	locationTrusted bool
+	jar             *CookieJar
+	compressed      bool
+	retry           *RetryPolicy
+	onRetry         func(Attempt)
+}
+
+type ClientOption func(*Client)
+
+func NewClient(opts ...ClientOption) *Client {
+	dialer := &net.Dialer{
+		Timeout:   DefaultConnectTimeout,
 		KeepAlive: 30 * time.Second,
 	}
 
-	transport *http.Transport = &http.Transport{
-		DialContext:           dialer.DialContext,
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
 		ForceAttemptHTTP2:     false,
 		MaxIdleConns:          200,
 		MaxIdleConnsPerHost:   50,
 		IdleConnTimeout:       60 * time.Second,
-		ResponseHeaderTimeout: 15 * time.Second,
-		TLSHandshakeTimeout:   8 * time.Second,
+		ResponseHeaderTimeout: DefaultResponseHeaderTimeout,
+		TLSHandshakeTimeout:   DefaultTLSHandshakeTimeout,
 		ExpectContinueTimeout: 1 * time.Second,
 		DisableCompression:    true,
 		TLSClientConfig: &tls.Config{
@@ -32,48 +59,273 @@ var (
 		},
 	}
 
-	client *http.Client = &http.Client{
-		Transport: transport,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
+	c := &Client{
+		dialer:    dialer,
+		transport: transport,
+		client: &http.Client{
+			Transport:     transport,
+			CheckRedirect: noRedirects,
 		},
 	}
-)
+	transport.DialContext = c.dialContext
 
-type Client struct {
-	client    *http.Client
-	transport *http.Transport
-	dialer    *net.Dialer
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
 }
 
-type ClientOption func(*http.Client, *http.Transport, *net.Dialer)
+// WithConnectTimeout limits establishing the TCP connection.
+func WithConnectTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.dialer.Timeout = d
+	}
+}
 
-func NewClient(opts ...ClientOption) *Client {
-	for _, opt := range opts {
-		opt(client, transport, dialer)
+// WithTLSHandshakeTimeout limits the TLS handshake. zero means no limit.
+func WithTLSHandshakeTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.transport.TLSHandshakeTimeout = d
+	}
+}
+
+// WithResponseHeaderTimeout limits waiting for the response headers after
+// the request was written. zero means no limit.
+func WithResponseHeaderTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.transport.ResponseHeaderTimeout = d
 	}
+}
 
-	return &Client{client: client, transport: transport, dialer: dialer}
+// WithTimeout limits the whole exchange, including reading the body.
+func WithTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.client.Timeout = d
+	}
 }
 
-func (c *Client) Send(req *RequestConf) (*Response, error) {
-	r := req.ToHTTP()
+// WithTLSConfig replaces the TLS configuration. the config is cloned, so the
+// caller may keep changing its own copy.
+func WithTLSConfig(cfg *tls.Config) ClientOption {
+	return func(c *Client) {
+		c.transport.TLSClientConfig = cfg.Clone()
+	}
+}
 
-	resp, err := client.Do(r)
-	if err != nil {
-		return nil, fmt.Errorf("doing request: %w", err)
+// WithProtocols sets the HTTP versions the transport may use, see HTTP2 and
+// HTTP2PriorKnowledge.
+func WithProtocols(p http.Protocols) ClientOption {
+	return func(c *Client) {
+		c.transport.Protocols = &p
+	}
+}
+
+// WithProxy sets the function choosing a proxy for each request. nil
+// disables proxies, including the ones from the environment.
+func WithProxy(proxy func(*http.Request) (*url.URL, error)) ClientOption {
+	return func(c *Client) {
+		c.transport.Proxy = proxy
+	}
+}
+
+// WithProxyURL sends every request through the given proxy.
+func WithProxyURL(u *url.URL) ClientOption {
+	return WithProxy(http.ProxyURL(u))
+}
+
+// WithRedirects follows up to max redirects. zero, the default, returns the
+// redirect response itself.
+func WithRedirects(max int) ClientOption {
+	return func(c *Client) {
+		c.maxRedirects = max
+	}
+}
+
+// WithLocationTrusted keeps sending Authorization and Cookie headers when a
+// redirect leads to another host.
+func WithLocationTrusted(trusted bool) ClientOption {
+	return func(c *Client) {
+		c.locationTrusted = trusted
 	}
-	defer resp.Body.Close()
+}
 
-	res := Response{resp: resp}
+// WithCookieJar sends matching cookies from the jar with every request and
+// stores the cookies set by every response, redirects included.
+func WithCookieJar(jar *CookieJar) ClientOption {
+	return func(c *Client) {
+		c.jar = jar
+	}
+}
 
-	data, err := io.ReadAll(resp.Body)
+// WithCompression asks for compressed responses with Accept-Encoding, unless
+// the request sets its own, and decodes gzip, deflate, br and zstd bodies.
+func WithCompression(on bool) ClientOption {
+	return func(c *Client) {
+		c.compressed = on
+	}
+}
+
+// WithRetry sends failed requests again according to the policy.
+func WithRetry(p RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.retry = &p
+	}
+}
+
+// WithRetryNotify calls notify before waiting for each retry, e.g. to tell
+// the user why nothing happens for a while.
+func WithRetryNotify(notify func(Attempt)) ClientOption {
+	return func(c *Client) {
+		c.onRetry = notify
+	}
+}
+
+// WithTransport replaces the round tripper, e.g. to record or mock requests.
+// options touching the transport have no effect afterwards.
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(c *Client) {
+		c.client.Transport = rt
+	}
+}
+
+func noRedirects(req *http.Request, via []*http.Request) error {
+	return http.ErrUseLastResponse
+}
+
+// CloseIdleConnections closes connections kept alive by previous requests.
+func (c *Client) CloseIdleConnections() {
+	c.client.CloseIdleConnections()
+}
+
+// exchange is a request with the redirects it went through. the body of
+// resp is left unread.
+type exchange struct {
+	req       *http.Request // the last hop
+	resp      *http.Response
+	redirects []Redirect
+	trace     *tracer
+	cookies   []*http.Cookie // the user cookies sent with req
+	// hops are the redirects before req
+	hops []Hop
+}
+
+// exchange sends r and follows its redirects if enabled. user are the
+// cookies the user set on r, see nextRequest.
+func (c *Client) exchange(ctx context.Context, r *http.Request, user []*http.Cookie) (ex exchange, err error) {
+	ex.cookies = user
+	for {
+		var traceCtx context.Context
+		ex.trace, traceCtx = newTracer(ctx)
+
+		ex.req = r
+		ex.resp, err = c.client.Do(r.WithContext(traceCtx))
+		if err != nil {
+			if ctx.Err() != nil {
+				return ex, fmt.Errorf("request aborted: %w", context.Cause(ctx))
+			}
+			return ex, fmt.Errorf("doing request: %w", err)
+		}
+
+		if c.jar != nil {
+			c.jar.SetCookies(r.URL, ex.resp.Cookies())
+		}
+
+		next, cookies, err := c.nextRequest(ctx, r, ex.resp, len(ex.redirects), ex.cookies)
+		if err != nil {
+			ex.resp.Body.Close()
+			ex.resp = nil
+			return ex, err
+		}
+		if next == nil {
+			return ex, nil
+		}
+
+		ex.redirects = append(ex.redirects, newRedirect(r, ex.resp))
+		drain(ex.resp.Body)
+		ex.hops = append(ex.hops, newHop(r, ex.resp, ex.trace))
+		r, ex.cookies = next, cookies
+	}
+}
+
+// Send does the request, following redirects and retrying if enabled, and
+// reads the whole body. cancelling ctx aborts both, e.g. on Ctrl-C during a
+// slow download.
+func (c *Client) Send(ctx context.Context, req *RequestConf) (*Response, error) {
+	res, err := c.Stream(ctx, req)
 	if err != nil {
-		// how do i handle this? i still should return a response, but with no body
-		return &res, fmt.Errorf("copying response body: %w", err)
+		return nil, err
+	}
+	return res, res.consume(io.Discard, -1)
+}
+
+// Stream is Send without reading the body. the response has to be read with
+// Consume or closed, and ctx still aborts reading it.
+func (c *Client) Stream(ctx context.Context, req *RequestConf) (*Response, error) {
+	user := req.userCookies()
+	r := req.ToHTTP().WithContext(ctx)
+	c.setCookies(r, user)
+	if c.compressed && r.Header.Get("Accept-Encoding") == "" {
+		r.Header.Set("Accept-Encoding", AcceptEncoding)
+	}
+
+	started := time.Now()
+
+	replayable := r.Body == nil || r.Body == http.NoBody || r.GetBody != nil
+
+	var ex exchange
+	var attempts []Attempt
+	var hops []Hop
+	for n := 1; ; n++ {
+		var err error
+		ex, err = c.exchange(ctx, r, user)
+		hops = append(hops, ex.hops...)
+
+		attempt, retry := c.retry.next(n, started, ex.resp, err)
+		if !retry || !replayable || ctx.Err() != nil {
+			if err != nil && len(attempts) != 0 {
+				err = fmt.Errorf("giving up after %d attempts: %w", n, err)
+			}
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+
+		attempts = append(attempts, attempt)
+		if c.onRetry != nil {
+			c.onRetry(attempt)
+		}
+		if ex.resp != nil {
+			drain(ex.resp.Body)
+			hops = append(hops, newHop(ex.req, ex.resp, ex.trace))
+		}
+
+		if err = sleep(ctx, attempt.Wait); err != nil {
+			return nil, fmt.Errorf("request aborted: %w", err)
+		}
+		if r, err = replay(r); err != nil {
+			return nil, err
+		}
+		c.setCookies(r, user)
 	}
 
-	res.body = data
+	res := Response{
+		resp:      ex.resp,
+		final:     ex.req,
+		cookies:   ex.cookies,
+		ctx:       ctx,
+		trace:     ex.trace,
+		started:   started,
+		redirects: ex.redirects,
+		attempts:  attempts,
+		hops:      hops,
+		proxy:     c.proxyFor(ex.req),
+		socket:    c.dial.unixSocket,
+	}
+	if c.compressed {
+		res.decode = strings.Join(ex.resp.Header.Values("Content-Encoding"), ", ")
+	}
 
 	return &res, nil
 }
@@ -0,0 +1,316 @@
+package httpcore
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClient_Isolated(t *testing.T) {
+	a := NewClient(
+		WithConnectTimeout(time.Second),
+		WithResponseHeaderTimeout(2*time.Second),
+		WithTLSConfig(&tls.Config{InsecureSkipVerify: true}),
+	)
+	b := NewClient()
+
+	assert.NotSame(t, a.transport, b.transport)
+	assert.NotSame(t, a.dialer, b.dialer)
+
+	assert.Equal(t, time.Second, a.dialer.Timeout)
+	assert.Equal(t, DefaultConnectTimeout, b.dialer.Timeout)
+	assert.Equal(t, 2*time.Second, a.transport.ResponseHeaderTimeout)
+	assert.Equal(t, DefaultResponseHeaderTimeout, b.transport.ResponseHeaderTimeout)
+	assert.True(t, a.transport.TLSClientConfig.InsecureSkipVerify)
+	assert.False(t, b.transport.TLSClientConfig.InsecureSkipVerify)
+}
+
+func TestClient_Redirects(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "auth=%q cookie=%q", r.Header.Get("Authorization"), r.Header.Get("Cookie"))
+	}))
+	defer other.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/found":
+			http.SetCookie(w, &http.Cookie{Name: "step", Value: "1"})
+			http.Redirect(w, r, "/see-other", http.StatusFound)
+		case "/see-other":
+			http.Redirect(w, r, "/echo", http.StatusSeeOther)
+		case "/temporary":
+			http.Redirect(w, r, "/echo", http.StatusTemporaryRedirect)
+		case "/away":
+			http.Redirect(w, r, other.URL+"/", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			fmt.Fprintf(w, "%s %s cookie=%q", r.Method, body, r.Header.Get("Cookie"))
+		}
+	}))
+	defer srv.Close()
+
+	payload := "payload"
+	send := func(c *Client, method, path string) (*Response, error) {
+		req, err := NewRequestFromSerializable(RequestSerializable{
+			Method:  method,
+			URL:     srv.URL + path,
+			Headers: map[string][]string{"Authorization": {"Bearer secret"}},
+			Body:    &BodySpec{Type: "content", Text: &payload},
+		})
+		require.NoError(t, err)
+		return c.Send(context.Background(), req)
+	}
+
+	resp, err := send(NewClient(), "POST", "/found")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusFound, resp.ToHTTP().StatusCode)
+	assert.Empty(t, resp.Redirects())
+
+	jar := NewCookieJar()
+	resp, err = send(NewClient(WithRedirects(5), WithCookieJar(jar)), "POST", "/found")
+	require.NoError(t, err)
+	assert.Equal(t, `GET  cookie="step=1"`, string(resp.Body()))
+	require.Len(t, resp.Redirects(), 2)
+	assert.Equal(t, Redirect{
+		Method:     "POST",
+		URL:        srv.URL + "/found",
+		StatusCode: http.StatusFound,
+		Location:   "/see-other",
+		SetCookies: []string{"step=1"},
+	}, resp.Redirects()[0])
+	assert.Equal(t, "GET", resp.Redirects()[1].Method)
+
+	resp, err = send(NewClient(WithRedirects(5)), "PUT", "/temporary")
+	require.NoError(t, err)
+	assert.Equal(t, `PUT payload cookie=""`, string(resp.Body()))
+
+	resp, err = send(NewClient(WithRedirects(5)), "GET", "/away")
+	require.NoError(t, err)
+	assert.Equal(t, `auth="" cookie=""`, string(resp.Body()))
+
+	resp, err = send(NewClient(WithRedirects(5), WithLocationTrusted(true)), "GET", "/away")
+	require.NoError(t, err)
+	assert.Equal(t, `auth="Bearer secret" cookie=""`, string(resp.Body()))
+
+	_, err = send(NewClient(WithRedirects(3)), "GET", "/loop")
+	assert.ErrorContains(t, err, "stopped after 3 redirects")
+}
+
+func TestClient_RedirectCookies(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "new", Path: "/"})
+			http.SetCookie(w, &http.Cookie{Name: "step", Value: "", Path: "/", MaxAge: -1})
+			http.Redirect(w, r, "/home", http.StatusFound)
+		default:
+			fmt.Fprintf(w, "cookie=%q", r.Header.Get("Cookie"))
+		}
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	jar := NewCookieJar()
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "session", Value: "old", Path: "/"},
+		{Name: "step", Value: "1", Path: "/"},
+		{Name: "scoped", Value: "1", Path: "/login"},
+	})
+
+	req, err := NewRequest(srv.URL + "/login")
+	require.NoError(t, err)
+	req.AddCookie("user", "explicit")
+	// like the http command does, to show the jar cookies
+	jar.Attach(req)
+	assert.Equal(t, "user=explicit; scoped=1; session=old; step=1", req.ToHTTP().Header.Get("Cookie"))
+
+	resp, err := NewClient(WithRedirects(5), WithCookieJar(jar)).Send(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, `cookie="user=explicit; session=new"`, string(resp.Body()))
+}
+
+func TestClient_Concurrent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.Query().Get("n"))
+	}))
+	defer srv.Close()
+
+	client := NewClient(WithTimeout(5 * time.Second))
+
+	var wg sync.WaitGroup
+	for i := range 32 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			req, err := NewRequest(fmt.Sprintf("%s/?n=%d", srv.URL, i))
+			if !assert.NoError(t, err) {
+				return
+			}
+			resp, err := client.Send(context.Background(), req)
+			if assert.NoError(t, err) {
+				assert.Equal(t, fmt.Sprint(i), string(resp.Body()))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestClient_Cancel(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	req, err := NewRequest(srv.URL)
+	require.NoError(t, err)
+
+	_, err = NewClient().Send(ctx, req)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorContains(t, err, "request aborted while reading the body")
+
+	_, err = NewClient(WithTimeout(100*time.Millisecond)).Send(context.Background(), req)
+	assert.Error(t, err)
+}
+
+func TestClient_Timing(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		fmt.Fprint(w, "ok")
+	}))
+	defer srv.Close()
+
+	client := NewClient(WithTransport(srv.Client().Transport))
+
+	send := func() *Timing {
+		req, err := NewRequest(srv.URL)
+		require.NoError(t, err)
+		resp, err := client.Send(context.Background(), req)
+		require.NoError(t, err)
+		require.NotNil(t, resp.Timing())
+		return resp.Timing()
+	}
+
+	first := send()
+	assert.False(t, first.Reused)
+	assert.Positive(t, first.TCPConnect)
+	assert.Positive(t, first.TLSHandshake)
+	assert.GreaterOrEqual(t, first.Wait, 20*time.Millisecond)
+	assert.GreaterOrEqual(t, first.TTFB, first.Wait)
+	assert.GreaterOrEqual(t, first.Total, first.TTFB)
+
+	second := send()
+	assert.True(t, second.Reused)
+	assert.Zero(t, second.TLSHandshake)
+
+	tree := first.Tree().String()
+	assert.Contains(t, tree, "TLS handshake")
+	assert.Contains(t, tree, "new connection to "+srv.Listener.Addr().String())
+
+	data, err := json.Marshal(first)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"reused":false`)
+	assert.Contains(t, string(data), `"ttfb_ms":`)
+}
+
+func TestClient_Stream(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n")
+	body := append(png, bytes.Repeat([]byte{0}, 3*PreviewSize)...)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+		// no Content-Type, so it has to be sniffed
+		w.Header()["Content-Type"] = nil
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	req, err := NewRequest(srv.URL)
+	require.NoError(t, err)
+
+	resp, err := NewClient().Stream(context.Background(), req)
+	require.NoError(t, err)
+	assert.Nil(t, resp.Timing())
+
+	// neither peeking at the content type nor printing reads the body away
+	assert.Equal(t, "image/png", resp.ContentType())
+	_, err = resp.ToString()
+	require.NoError(t, err)
+
+	var received, total int64
+	resp.OnProgress(func(n, t int64) { received, total = n, t })
+
+	var out bytes.Buffer
+	require.NoError(t, resp.Consume(&out))
+	assert.Equal(t, body, out.Bytes())
+	assert.Equal(t, body[:PreviewSize], resp.Body())
+	assert.Equal(t, int64(len(body)), resp.Size())
+	assert.Equal(t, int64(len(body)), received)
+	assert.Equal(t, int64(len(body)), total)
+	assert.NotNil(t, resp.Timing())
+
+	str, err := resp.ToString()
+	require.NoError(t, err)
+	assert.Contains(t, str, "Body: 3.0 MB of image/png")
+
+	assert.ErrorContains(t, resp.Consume(io.Discard), "already read")
+}
+
+func TestClient_Stream_Compressed(t *testing.T) {
+	plain := bytes.Repeat([]byte("streamed and compressed\n"), 10000)
+	gz := encode(t, "gzip", plain)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		if r.Method == http.MethodHead {
+			return
+		}
+		w.Write(gz)
+	}))
+	defer srv.Close()
+
+	req, err := NewRequest(srv.URL)
+	require.NoError(t, err)
+
+	resp, err := NewClient(WithCompression(true)).Stream(context.Background(), req)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, resp.Consume(&out))
+	assert.Equal(t, plain, out.Bytes())
+	assert.Equal(t, int64(len(plain)), resp.Size())
+	assert.Equal(t, int64(len(gz)), resp.WireSize())
+	assert.Equal(t, "gzip", resp.Encoding())
+
+	// an empty body has nothing to decode
+	req, err = NewRequest(srv.URL)
+	require.NoError(t, err)
+	req.SetMethod(http.MethodHead)
+
+	resp, err = NewClient(WithCompression(true)).Send(context.Background(), req)
+	require.NoError(t, err)
+	assert.Empty(t, resp.Body())
+	assert.Empty(t, resp.Encoding())
+}
@@ -0,0 +1,111 @@
+package httpcore
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// AcceptEncoding lists every content coding the client can decode.
+const AcceptEncoding = "gzip, deflate, br, zstd"
+
+// decoder reads a body through one reader per content coding.
+type decoder struct {
+	io.Reader
+	closers []func()
+}
+
+func (d *decoder) Close() error {
+	for i := len(d.closers) - 1; i >= 0; i-- {
+		d.closers[i]()
+	}
+	return nil
+}
+
+// newDecoder undoes the content codings of a Content-Encoding header while
+// r is read. they are listed in the order they were applied, so decoding
+// goes backwards.
+func newDecoder(r io.Reader, contentEncoding string) (*decoder, error) {
+	d := &decoder{Reader: r}
+
+	codings := strings.Split(contentEncoding, ",")
+	for i := len(codings) - 1; i >= 0; i-- {
+		coding := strings.ToLower(strings.TrimSpace(codings[i]))
+		if coding == "" || coding == "identity" {
+			continue
+		}
+
+		next, closer, err := decodeReader(d.Reader, coding)
+		if err != nil {
+			d.Close()
+			return nil, fmt.Errorf("decoding %s body: %w", coding, err)
+		}
+		d.Reader = &codingReader{r: next, coding: coding}
+		if closer != nil {
+			d.closers = append(d.closers, closer)
+		}
+	}
+
+	return d, nil
+}
+
+// codingReader names the coding in errors that only show up while reading,
+// like a truncated or corrupt stream.
+type codingReader struct {
+	r      io.Reader
+	coding string
+}
+
+func (c *codingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("decoding %s body: %w", c.coding, err)
+	}
+	return n, err
+}
+
+func decodeReader(r io.Reader, coding string) (io.Reader, func(), error) {
+	switch coding {
+	case "gzip", "x-gzip":
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return gr, func() { gr.Close() }, nil
+	case "deflate":
+		// deflate is meant to be zlib wrapped, but some servers send it raw
+		br := bufio.NewReader(r)
+		if header, err := br.Peek(2); err == nil && isZlibHeader(header) {
+			zr, err := zlib.NewReader(br)
+			if err != nil {
+				return nil, nil, err
+			}
+			return zr, func() { zr.Close() }, nil
+		}
+		fr := flate.NewReader(br)
+		return fr, func() { fr.Close() }, nil
+	case "br":
+		return brotli.NewReader(r), nil, nil
+	case "zstd":
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return zr, zr.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported content coding")
+	}
+}
+
+// isZlibHeader checks the compression method and the header checksum of
+// RFC 1950.
+func isZlibHeader(h []byte) bool {
+	return h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0
+}
@@ -0,0 +1,132 @@
+package httpcore
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encode(t *testing.T, coding string, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch coding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		require.NoError(t, err)
+		w = fw
+	case "br":
+		w = brotli.NewWriter(&buf)
+	case "zstd":
+		zw, err := zstd.NewWriter(&buf)
+		require.NoError(t, err)
+		w = zw
+	default:
+		t.Fatalf("unknown coding %s", coding)
+	}
+
+	_, err := w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+// decode reads data whole through newDecoder.
+func decode(data []byte, contentEncoding string) ([]byte, error) {
+	r, err := newDecoder(bytes.NewReader(data), contentEncoding)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+func TestNewDecoder(t *testing.T) {
+	plain := []byte(strings.Repeat(`{"name":"ghostman"}`, 50))
+
+	for _, coding := range []string{"gzip", "deflate", "br", "zstd"} {
+		t.Run(coding, func(t *testing.T) {
+			decoded, err := decode(encode(t, coding, plain), coding)
+			require.NoError(t, err)
+			assert.Equal(t, plain, decoded)
+		})
+	}
+
+	t.Run("raw deflate", func(t *testing.T) {
+		decoded, err := decode(encode(t, "raw-deflate", plain), "deflate")
+		require.NoError(t, err)
+		assert.Equal(t, plain, decoded)
+	})
+
+	t.Run("stacked codings", func(t *testing.T) {
+		data := encode(t, "br", encode(t, "gzip", plain))
+		decoded, err := decode(data, "gzip, br")
+		require.NoError(t, err)
+		assert.Equal(t, plain, decoded)
+	})
+
+	_, err := decode(plain, "gzip")
+	assert.ErrorContains(t, err, "decoding gzip body")
+
+	_, err = decode(plain, "compress")
+	assert.ErrorContains(t, err, "unsupported content coding")
+}
+
+func TestClient_Compression(t *testing.T) {
+	plain := []byte(strings.Repeat("hello compressed world\n", 100))
+	zstdBody := encode(t, "zstd", plain)
+
+	var gotAccept string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAccept = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Encoding", "zstd")
+		w.Write(zstdBody)
+	}))
+	defer srv.Close()
+
+	req, err := NewRequest(srv.URL)
+	require.NoError(t, err)
+
+	resp, err := NewClient(WithCompression(true)).Send(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, AcceptEncoding, gotAccept)
+	assert.Equal(t, plain, resp.Body())
+	assert.Equal(t, int64(len(zstdBody)), resp.WireSize())
+	assert.Equal(t, "zstd", resp.Encoding())
+	assert.True(t, resp.Decodes())
+
+	str, err := resp.ToString()
+	require.NoError(t, err)
+	assert.Contains(t, str, "Body: 2.2 KB of text/plain, "+FormatBytes(int64(len(zstdBody)))+" zstd on the wire")
+	assert.Contains(t, str, "Content-Encoding: zstd")
+
+	// without compression the body is kept as received
+	req, err = NewRequest(srv.URL)
+	require.NoError(t, err)
+
+	resp, err = NewClient().Send(context.Background(), req)
+	require.NoError(t, err)
+	assert.Empty(t, gotAccept)
+	assert.Equal(t, zstdBody, resp.Body())
+	assert.Empty(t, resp.Encoding())
+	assert.False(t, resp.Decodes())
+}
@@ -1,33 +1,419 @@
 package httpcore
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/bigelle/ghostman/internal/shared"
+	"golang.org/x/net/publicsuffix"
 )
 
-//FIXME: ignoring for now
-
-//type CookieJar map[string]Cookie
-//
-//func (j CookieJar) Get(d string) *Cookie {
-//	c := j[d]
-//	return &c
-//}
-//
-//func (j *CookieJar) Load(r io.Reader) error {
-//	dec := json.NewDecoder(r)
-//	dec.DisallowUnknownFields()
-//	return dec.Decode(j)
-//}
-//
-//func (j CookieJar) Save(w io.Writer) error {
-//	enc := json.NewEncoder(w)
-//	enc.SetIndent("", "  ")
-//	return enc.Encode(j)
-//}
+// CookieJar stores cookies following RFC 6265 domain, path, expiry and
+// secure matching. it implements http.CookieJar and can be saved to a file,
+// so a login from one run is reused by later ones.
+type CookieJar struct {
+	mu      sync.Mutex
+	cookies []JarCookie
+
+	// Netscape makes Save write the cookies.txt format instead of JSON. it's
+	// set when the jar was loaded from one.
+	Netscape bool
+}
+
+// JarCookie is a cookie as the jar keeps it: the domain is always set and
+// lowercase, Expires is absolute, and a zero Expires means a session cookie.
+type JarCookie struct {
+	Cookie
+	HostOnly bool      `json:"host_only,omitzero"`
+	Created  time.Time `json:"created"`
+}
+
+func NewCookieJar() *CookieJar {
+	return &CookieJar{}
+}
+
+// LoadCookieJarFile reads a jar saved with SaveFile or a cookies.txt file. a
+// missing file gives an empty jar, in the Netscape format if the path ends
+// with .txt.
+func LoadCookieJarFile(path string) (*CookieJar, error) {
+	j := NewCookieJar()
+	j.Netscape = isNetscapePath(path)
+
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening cookie jar: %w", err)
+	}
+	defer f.Close()
+
+	if err = j.Load(f); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Load replaces the jar contents, detecting JSON or the cookies.txt format.
+func (j *CookieJar) Load(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("reading cookie jar: %w", err)
+	}
+
+	var cookies []JarCookie
+	netscape := IsNetscapeCookies(data)
+
+	if netscape {
+		cookies, err = ParseNetscapeCookies(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("decoding cookies.txt: %w", err)
+		}
+	} else if len(bytes.TrimSpace(data)) != 0 {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err = dec.Decode(&cookies); err != nil {
+			return fmt.Errorf("decoding cookie jar: %w", err)
+		}
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if netscape {
+		j.Netscape = true
+	}
+
+	j.cookies = j.cookies[:0]
+	for _, c := range cookies {
+		if c.Name == "" || c.Domain == "" {
+			continue
+		}
+		j.store(c)
+	}
+	j.purge(time.Now())
+
+	return nil
+}
+
+func (j *CookieJar) Save(w io.Writer) error {
+	j.mu.Lock()
+	netscape := j.Netscape
+	j.mu.Unlock()
+
+	return j.save(w, netscape)
+}
+
+func (j *CookieJar) save(w io.Writer, netscape bool) error {
+	j.mu.Lock()
+	j.purge(time.Now())
+	cookies := slices.Clone(j.cookies)
+	j.mu.Unlock()
+
+	if netscape {
+		return WriteNetscapeCookies(w, cookies)
+	}
+
+	if cookies == nil {
+		cookies = []JarCookie{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cookies)
+}
+
+// SaveFile replaces the file atomically, so an interrupted run never leaves
+// a truncated jar behind. a .txt path is always written as cookies.txt.
+func (j *CookieJar) SaveFile(path string) error {
+	j.mu.Lock()
+	netscape := j.Netscape || isNetscapePath(path)
+	j.mu.Unlock()
+
+	return j.saveFile(path, netscape)
+}
+
+// ExportFile writes the jar to path as cookies.txt if it ends with .txt and
+// as JSON otherwise, whatever format the jar was loaded from. it converts
+// jars between ghostman and curl.
+func (j *CookieJar) ExportFile(path string) error {
+	return j.saveFile(path, isNetscapePath(path))
+}
+
+func (j *CookieJar) saveFile(path string, netscape bool) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".*.jar")
+	if err != nil {
+		return fmt.Errorf("creating temporary cookie jar: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	// cookies are credentials, keep them private
+	if err = tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("creating temporary cookie jar: %w", err)
+	}
+
+	if err = j.save(tmp, netscape); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing cookie jar: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("writing cookie jar: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replacing cookie jar: %w", err)
+	}
+
+	return nil
+}
+
+// All returns every unexpired cookie in the jar.
+func (j *CookieJar) All() []JarCookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.purge(time.Now())
+	return slices.Clone(j.cookies)
+}
+
+// SetCookies stores cookies received from u. cookies with an invalid domain
+// are dropped, expired ones delete what they replace.
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	host, err := canonicalHost(u)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, hc := range cookies {
+		c, ok := newJarCookie(hc, host, u.Path, now)
+		if !ok {
+			continue
+		}
+
+		if !c.Expires.IsZero() && !c.Expires.After(now) {
+			j.remove(c.Name, c.Domain, c.Path)
+			continue
+		}
+		j.store(c)
+	}
+}
+
+// Cookies returns the cookies to send to u, longest paths first.
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	host, err := canonicalHost(u)
+	if err != nil {
+		return nil
+	}
+	secure := isSecureURL(u, host)
+
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.purge(time.Now())
+
+	var matched []JarCookie
+	for _, c := range j.cookies {
+		if c.HostOnly && host != c.Domain {
+			continue
+		}
+		if !c.HostOnly && !domainMatch(host, c.Domain) {
+			continue
+		}
+		if !pathMatch(path, c.Path) || (c.Secure && !secure) {
+			continue
+		}
+		matched = append(matched, c)
+	}
+
+	slices.SortStableFunc(matched, func(a, b JarCookie) int {
+		if len(a.Path) != len(b.Path) {
+			return len(b.Path) - len(a.Path)
+		}
+		return a.Created.Compare(b.Created)
+	})
+
+	out := make([]*http.Cookie, len(matched))
+	for i, c := range matched {
+		out[i] = &http.Cookie{Name: c.Name, Value: c.Value}
+	}
+	return out
+}
+
+// Attach adds the cookies matching the request URL. cookies already set on
+// the request, e.g. with --cookie, take priority over stored ones.
+func (j *CookieJar) Attach(req *RequestConf) {
+	req.jarCookies = append(req.jarCookies, j.attach(req.ToHTTP())...)
+}
+
+// attach adds the matching cookies not set on r yet and returns their names.
+func (j *CookieJar) attach(r *http.Request) []string {
+	set := make(map[string]bool)
+	for _, c := range r.Cookies() {
+		set[c.Name] = true
+	}
+
+	var added []string
+	for _, c := range j.Cookies(r.URL) {
+		if !set[c.Name] {
+			r.AddCookie(c)
+			added = append(added, c.Name)
+		}
+	}
+	return added
+}
+
+func (j *CookieJar) store(c JarCookie) {
+	for i, old := range j.cookies {
+		if old.Name == c.Name && old.Domain == c.Domain && old.Path == c.Path {
+			// a replaced cookie keeps its creation time, RFC 6265 5.3 step 11
+			c.Created = old.Created
+			j.cookies[i] = c
+			return
+		}
+	}
+	j.cookies = append(j.cookies, c)
+}
+
+func (j *CookieJar) remove(name, domain, path string) {
+	j.cookies = slices.DeleteFunc(j.cookies, func(c JarCookie) bool {
+		return c.Name == name && c.Domain == domain && c.Path == path
+	})
+}
+
+func (j *CookieJar) purge(now time.Time) {
+	j.cookies = slices.DeleteFunc(j.cookies, func(c JarCookie) bool {
+		return !c.Expires.IsZero() && !c.Expires.After(now)
+	})
+}
+
+func newJarCookie(hc *http.Cookie, host, reqPath string, now time.Time) (JarCookie, bool) {
+	if hc.Name == "" {
+		return JarCookie{}, false
+	}
+
+	c := JarCookie{
+		Cookie: Cookie{
+			Name:        hc.Name,
+			Value:       hc.Value,
+			HttpOnly:    hc.HttpOnly,
+			Partitioned: hc.Partitioned,
+			Secure:      hc.Secure,
+			SameSite:    SameSite(hc.SameSite),
+		},
+		Created: now,
+	}
+
+	domain := strings.ToLower(strings.TrimPrefix(hc.Domain, "."))
+	switch {
+	case domain == "" || domain == host:
+		c.Domain, c.HostOnly = host, true
+	case net.ParseIP(host) != nil:
+		// IP addresses only ever set host-only cookies
+		return JarCookie{}, false
+	case !domainMatch(host, domain):
+		return JarCookie{}, false
+	case isPublicSuffix(domain):
+		return JarCookie{}, false
+	default:
+		c.Domain = domain
+	}
+
+	c.Path = hc.Path
+	if !strings.HasPrefix(c.Path, "/") {
+		c.Path = defaultPath(reqPath)
+	}
+
+	// Max-Age wins over Expires. http.Cookie keeps "Max-Age=0" as -1
+	switch {
+	case hc.MaxAge < 0:
+		c.Expires = CookieTime{now.Add(-time.Second)}
+	case hc.MaxAge > 0:
+		c.Expires = CookieTime{now.Add(time.Duration(hc.MaxAge) * time.Second)}
+	case !hc.Expires.IsZero():
+		c.Expires = CookieTime{hc.Expires}
+	}
+
+	return c, true
+}
+
+func canonicalHost(u *url.URL) (string, error) {
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return "", fmt.Errorf("no host in %s", u)
+	}
+	return host, nil
+}
+
+func isSecureURL(u *url.URL, host string) bool {
+	if u.Scheme == "https" || u.Scheme == "wss" {
+		return true
+	}
+	// browsers treat loopback as a secure context, so local setups work
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func isPublicSuffix(domain string) bool {
+	ps, _ := publicsuffix.PublicSuffix(domain)
+	return ps == domain
+}
+
+// domainMatch implements RFC 6265 5.1.3.
+func domainMatch(host, domain string) bool {
+	if host == domain {
+		return true
+	}
+	return net.ParseIP(host) == nil && strings.HasSuffix(host, "."+domain)
+}
+
+// pathMatch implements RFC 6265 5.1.4.
+func pathMatch(reqPath, cookiePath string) bool {
+	if reqPath == cookiePath {
+		return true
+	}
+	if !strings.HasPrefix(reqPath, cookiePath) {
+		return false
+	}
+	return strings.HasSuffix(cookiePath, "/") || reqPath[len(cookiePath)] == '/'
+}
+
+func defaultPath(reqPath string) string {
+	if !strings.HasPrefix(reqPath, "/") {
+		return "/"
+	}
+	i := strings.LastIndex(reqPath, "/")
+	if i == 0 {
+		return "/"
+	}
+	return reqPath[:i]
+}
 
 type Cookie struct {
 	Name        string     `json:"name"`
@@ -76,7 +462,7 @@ func (c Cookie) String() string {
 		fmt.Fprintf(buf, "Path=%s; ", c.Path)
 	}
 
-	if c.SameSite != SameSiteDefaultMode {
+	if c.SameSite != SameSiteDefaultMode && c.SameSite != 0 {
 		fmt.Fprintf(buf, "SameSite=%s; ", c.SameSite)
 	}
 
@@ -121,6 +507,9 @@ const (
 )
 
 func (s SameSite) String() string {
+	if s < SameSiteDefaultMode || s > SameSiteNoneMode {
+		return ""
+	}
 	return [...]string{"", "Lax", "Strict", "None"}[s-1]
 }
 
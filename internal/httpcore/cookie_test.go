@@ -1,7 +1,13 @@
 package httpcore
 
 import (
+	"bytes"
 	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -33,3 +39,181 @@ func TestCookie_Marshal(t *testing.T) {
 	assert.Equal(t, c, shouldBe)
 }
 
+
+func cookieNames(cookies []*http.Cookie) []string {
+	names := make([]string, len(cookies))
+	for i, c := range cookies {
+		names[i] = c.Name
+	}
+	return names
+}
+
+func TestCookieJar_Matching(t *testing.T) {
+	jar := NewCookieJar()
+	from, _ := url.Parse("https://www.example.com/account/login")
+
+	jar.SetCookies(from, []*http.Cookie{
+		{Name: "host", Value: "1"},
+		{Name: "domain", Value: "1", Domain: ".example.com", Path: "/"},
+		{Name: "api", Value: "1", Domain: "example.com", Path: "/api"},
+		{Name: "secure", Value: "1", Path: "/", Secure: true},
+		{Name: "psl", Value: "1", Domain: "com"},
+		{Name: "foreign", Value: "1", Domain: "other.org"},
+		{Name: "gone", Value: "1", MaxAge: -1},
+	})
+
+	testcases := []struct {
+		URL      string
+		Expected []string
+	}{
+		{"https://www.example.com/account/profile", []string{"host", "domain", "secure"}},
+		{"http://www.example.com/account/", []string{"host", "domain"}},
+		{"https://www.example.com/", []string{"domain", "secure"}},
+		{"https://api.example.com/api/v1", []string{"api", "domain"}},
+		{"https://api.example.com/apiv1", []string{"domain"}},
+		{"https://example.org/", []string{}},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.URL, func(t *testing.T) {
+			u, _ := url.Parse(tc.URL)
+			assert.Equal(t, tc.Expected, cookieNames(jar.Cookies(u)))
+		})
+	}
+}
+
+func TestCookieJar_Expiry(t *testing.T) {
+	jar := NewCookieJar()
+	u, _ := url.Parse("https://example.com/")
+
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "a", Value: "1", MaxAge: 60},
+		{Name: "b", Value: "1", Expires: time.Now().Add(-time.Hour)},
+	})
+	assert.Equal(t, []string{"a"}, cookieNames(jar.Cookies(u)))
+
+	jar.SetCookies(u, []*http.Cookie{{Name: "a", Value: "1", MaxAge: -1}})
+	assert.Empty(t, jar.Cookies(u))
+}
+
+func TestCookieJar_SaveLoad(t *testing.T) {
+	jar := NewCookieJar()
+	u, _ := url.Parse("https://example.com/")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "session", Value: "abc", HttpOnly: true},
+		{Name: "pref", Value: "dark", MaxAge: 3600},
+	})
+
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	require.NoError(t, jar.SaveFile(path))
+
+	loaded, err := LoadCookieJarFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"session", "pref"}, cookieNames(loaded.Cookies(u)))
+
+	var buf bytes.Buffer
+	require.NoError(t, loaded.Save(&buf))
+	assert.Contains(t, buf.String(), `"host_only": true`)
+
+	missing, err := LoadCookieJarFile(filepath.Join(t.TempDir(), "missing.json"))
+	require.NoError(t, err)
+	assert.Empty(t, missing.All())
+}
+
+func TestCookieJar_Attach(t *testing.T) {
+	jar := NewCookieJar()
+	u, _ := url.Parse("https://example.com/")
+	jar.SetCookies(u, []*http.Cookie{{Name: "a", Value: "stored"}, {Name: "b", Value: "stored"}})
+
+	req, err := NewRequest("https://example.com/")
+	require.NoError(t, err)
+	req.AddCookie("a", "explicit")
+
+	jar.Attach(req)
+	assert.Equal(t, "a=explicit; b=stored", req.ToHTTP().Header.Get("Cookie"))
+}
+
+const testCookiesTxt = `# Netscape HTTP Cookie File
+# https://curl.se/docs/http-cookies.html
+
+.example.com	TRUE	/	FALSE	0	theme	dark
+#HttpOnly_www.example.com	FALSE	/account	TRUE	4102444800	sid	abc123
+www.example.com	FALSE	/	FALSE	0	empty
+`
+
+func TestParseNetscapeCookies(t *testing.T) {
+	cookies, err := ParseNetscapeCookies(strings.NewReader(testCookiesTxt))
+	require.NoError(t, err)
+	require.Len(t, cookies, 3)
+
+	assert.Equal(t, "example.com", cookies[0].Domain)
+	assert.False(t, cookies[0].HostOnly)
+	assert.True(t, cookies[0].Expires.IsZero())
+
+	sid := cookies[1]
+	assert.Equal(t, "www.example.com", sid.Domain)
+	assert.True(t, sid.HostOnly)
+	assert.True(t, sid.HttpOnly)
+	assert.True(t, sid.Secure)
+	assert.Equal(t, "/account", sid.Path)
+	assert.Equal(t, time.Date(2100, time.January, 1, 0, 0, 0, 0, time.UTC), sid.Expires.Time)
+
+	assert.Equal(t, "empty", cookies[2].Name)
+	assert.Equal(t, "", cookies[2].Value)
+
+	_, err = ParseNetscapeCookies(strings.NewReader("example.com\tTRUE\t/\tFALSE\tsoon\ta\tb\n"))
+	assert.ErrorContains(t, err, "line 1: invalid expiry")
+}
+
+func TestCookieJar_Netscape(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies")
+	require.NoError(t, os.WriteFile(path, []byte(testCookiesTxt), 0o600))
+
+	jar, err := LoadCookieJarFile(path)
+	require.NoError(t, err)
+	assert.True(t, jar.Netscape)
+
+	u, _ := url.Parse("https://www.example.com/account/settings")
+	assert.Equal(t, []string{"sid", "theme", "empty"}, cookieNames(jar.Cookies(u)))
+
+	var buf bytes.Buffer
+	require.NoError(t, jar.Save(&buf))
+
+	roundTrip, err := ParseNetscapeCookies(&buf)
+	require.NoError(t, err)
+	original, _ := ParseNetscapeCookies(strings.NewReader(testCookiesTxt))
+	for i := range original {
+		original[i].Created, roundTrip[i].Created = time.Time{}, time.Time{}
+	}
+	assert.Equal(t, original, roundTrip)
+}
+
+func TestCookieJar_ExportFile(t *testing.T) {
+	dir := t.TempDir()
+	jar := NewCookieJar()
+	u, _ := url.Parse("https://example.com/")
+	jar.SetCookies(u, []*http.Cookie{{Name: "session", Value: "abc"}})
+	require.NoError(t, jar.SaveFile(filepath.Join(dir, "jar.json")))
+
+	loaded, err := LoadCookieJarFile(filepath.Join(dir, "jar.json"))
+	require.NoError(t, err)
+	require.NoError(t, loaded.ExportFile(filepath.Join(dir, "cookies.txt")))
+	// exporting doesn't change the format the jar is saved in
+	assert.False(t, loaded.Netscape)
+
+	data, err := os.ReadFile(filepath.Join(dir, "cookies.txt"))
+	require.NoError(t, err)
+	assert.True(t, IsNetscapeCookies(data))
+	cookies, err := ParseNetscapeCookies(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "abc", cookies[0].Value)
+
+	txt, err := LoadCookieJarFile(filepath.Join(dir, "cookies.txt"))
+	require.NoError(t, err)
+	require.NoError(t, txt.ExportFile(filepath.Join(dir, "back.json")))
+	data, err = os.ReadFile(filepath.Join(dir, "back.json"))
+	require.NoError(t, err)
+	assert.False(t, IsNetscapeCookies(data))
+	assert.True(t, json.Valid(data))
+}
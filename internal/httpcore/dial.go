@@ -0,0 +1,262 @@
+package httpcore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// ResolveRule pins a host and port to addresses instead of asking DNS, like
+// curl's --resolve. a "*" port matches every port.
+type ResolveRule struct {
+	Host  string
+	Port  string
+	Addrs []string
+}
+
+// ParseResolve reads "host:port:addr[,addr...]", IPv6 addresses in brackets.
+func ParseResolve(s string) (ResolveRule, error) {
+	parts, err := splitHostParts(s, 3)
+	if err != nil || parts[0] == "" || parts[2] == "" {
+		return ResolveRule{}, fmt.Errorf("invalid resolve rule %q, use host:port:address", s)
+	}
+	if err = checkPort(parts[1], true); err != nil {
+		return ResolveRule{}, fmt.Errorf("invalid resolve rule %q: %w", s, err)
+	}
+
+	rule := ResolveRule{Host: strings.ToLower(parts[0]), Port: parts[1]}
+	for _, addr := range strings.Split(parts[2], ",") {
+		addr = strings.Trim(strings.TrimSpace(addr), "[]")
+		if net.ParseIP(addr) == nil {
+			return ResolveRule{}, fmt.Errorf("invalid resolve rule %q: %q is not an IP address", s, addr)
+		}
+		rule.Addrs = append(rule.Addrs, addr)
+	}
+
+	return rule, nil
+}
+
+// ConnectToRule sends connections for Host:Port to ToHost:ToPort, like
+// curl's --connect-to. empty Host or Port match anything, empty ToHost or
+// ToPort keep the original.
+type ConnectToRule struct {
+	Host, Port     string
+	ToHost, ToPort string
+}
+
+// ParseConnectTo reads "host:port:tohost:toport", any of them may be empty.
+func ParseConnectTo(s string) (ConnectToRule, error) {
+	parts, err := splitHostParts(s, 4)
+	if err != nil {
+		return ConnectToRule{}, fmt.Errorf("invalid connect-to rule %q, use host:port:connect-to-host:connect-to-port", s)
+	}
+	for _, port := range []string{parts[1], parts[3]} {
+		if err = checkPort(port, false); err != nil {
+			return ConnectToRule{}, fmt.Errorf("invalid connect-to rule %q: %w", s, err)
+		}
+	}
+
+	return ConnectToRule{
+		Host:   strings.ToLower(strings.Trim(parts[0], "[]")),
+		Port:   parts[1],
+		ToHost: strings.Trim(parts[2], "[]"),
+		ToPort: parts[3],
+	}, nil
+}
+
+// splitHostParts splits s into n colon separated parts, leaving colons
+// inside brackets alone, and the last part whole.
+func splitHostParts(s string, n int) ([]string, error) {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s) && len(parts) < n-1; i++ {
+		switch s[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ':':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, s[start:])
+
+	if len(parts) != n {
+		return nil, errors.New("wrong number of fields")
+	}
+	return parts, nil
+}
+
+func checkPort(port string, wildcard bool) error {
+	if port == "" && !wildcard || port == "*" && wildcard {
+		return nil
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+	return nil
+}
+
+// ParseIPVersion accepts "4" or "6", and "" for either.
+func ParseIPVersion(s string) (int, error) {
+	switch s {
+	case "":
+		return 0, nil
+	case "4":
+		return 4, nil
+	case "6":
+		return 6, nil
+	}
+	return 0, fmt.Errorf("invalid IP version %q, use 4 or 6", s)
+}
+
+// dialRules change where the transport connects to, without touching the
+// URL, so Host and SNI stay the same.
+type dialRules struct {
+	resolve    []ResolveRule
+	connectTo  []ConnectToRule
+	ipVersion  int
+	unixSocket string
+}
+
+func (d dialRules) empty() bool {
+	return len(d.resolve) == 0 && len(d.connectTo) == 0 && d.ipVersion == 0 && d.unixSocket == ""
+}
+
+// WithResolve pins hosts to addresses, see ResolveRule.
+func WithResolve(rules ...ResolveRule) ClientOption {
+	return func(c *Client) {
+		c.dial.resolve = append(c.dial.resolve, rules...)
+	}
+}
+
+// WithConnectTo redirects connections to other hosts, see ConnectToRule.
+func WithConnectTo(rules ...ConnectToRule) ClientOption {
+	return func(c *Client) {
+		c.dial.connectTo = append(c.dial.connectTo, rules...)
+	}
+}
+
+// WithIPVersion only connects over IPv4 or IPv6, zero allows both.
+func WithIPVersion(v int) ClientOption {
+	return func(c *Client) {
+		c.dial.ipVersion = v
+	}
+}
+
+// WithUnixSocket connects to the Unix socket at path for every request,
+// keeping the URL's host and path. a path starting with "@" is an abstract
+// socket on Linux. proxies aren't used then.
+func WithUnixSocket(path string) ClientOption {
+	return func(c *Client) {
+		c.dial.unixSocket = path
+		c.transport.Proxy = nil
+	}
+}
+
+// WithDNSServers sends DNS queries to the given servers, "addr" or
+// "addr:port", instead of the system resolver. queries rotate through them.
+func WithDNSServers(servers ...string) ClientOption {
+	addrs := make([]string, len(servers))
+	for i, s := range servers {
+		addrs[i] = s
+		if _, _, err := net.SplitHostPort(s); err != nil {
+			addrs[i] = net.JoinHostPort(strings.Trim(s, "[]"), "53")
+		}
+	}
+
+	var next atomic.Uint32
+	return func(c *Client) {
+		c.dialer.Resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				server := addrs[int(next.Add(1)-1)%len(addrs)]
+				var d net.Dialer
+				return d.DialContext(ctx, network, server)
+			},
+		}
+	}
+}
+
+// dialContext applies connect-to and resolve rules before dialing with the
+// client's dialer.
+func (c *Client) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if c.dial.empty() {
+		return c.dialer.DialContext(ctx, network, addr)
+	}
+	if c.dial.unixSocket != "" {
+		return c.dialer.DialContext(ctx, "unix", c.dial.unixSocket)
+	}
+
+	switch {
+	case c.dial.ipVersion == 4 && network == "tcp":
+		network = "tcp4"
+	case c.dial.ipVersion == 6 && network == "tcp":
+		network = "tcp6"
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rule := range c.dial.connectTo {
+		if (rule.Host == "" || strings.EqualFold(rule.Host, host)) && (rule.Port == "" || rule.Port == port) {
+			if rule.ToHost != "" {
+				host = rule.ToHost
+			}
+			if rule.ToPort != "" {
+				port = rule.ToPort
+			}
+			break
+		}
+	}
+
+	addrs := c.resolved(host, port)
+	if addrs == nil {
+		return c.dialer.DialContext(ctx, network, net.JoinHostPort(host, port))
+	}
+
+	var errs []error
+	for _, ip := range addrs {
+		if !c.allowedIP(ip) {
+			continue
+		}
+		conn, err := c.dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no IPv%d address for %s among %s", c.dial.ipVersion, host, strings.Join(addrs, ", "))
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (c *Client) resolved(host, port string) []string {
+	for _, rule := range c.dial.resolve {
+		if strings.EqualFold(rule.Host, host) && (rule.Port == "*" || rule.Port == port) {
+			return rule.Addrs
+		}
+	}
+	return nil
+}
+
+func (c *Client) allowedIP(addr string) bool {
+	ip := net.ParseIP(addr)
+	switch c.dial.ipVersion {
+	case 4:
+		return ip.To4() != nil
+	case 6:
+		return ip.To4() == nil
+	}
+	return true
+}
@@ -0,0 +1,212 @@
+package httpcore
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestParseResolve(t *testing.T) {
+	rule, err := ParseResolve("Example.com:443:127.0.0.1,[::1]")
+	require.NoError(t, err)
+	assert.Equal(t, ResolveRule{Host: "example.com", Port: "443", Addrs: []string{"127.0.0.1", "::1"}}, rule)
+
+	rule, err = ParseResolve("example.com:*:10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "*", rule.Port)
+
+	for _, bad := range []string{"example.com:443", "example.com:http:127.0.0.1", ":443:127.0.0.1", "example.com:443:localhost"} {
+		_, err = ParseResolve(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestParseConnectTo(t *testing.T) {
+	rule, err := ParseConnectTo("example.com:443:[::1]:8443")
+	require.NoError(t, err)
+	assert.Equal(t, ConnectToRule{Host: "example.com", Port: "443", ToHost: "::1", ToPort: "8443"}, rule)
+
+	rule, err = ParseConnectTo("::backend:")
+	require.NoError(t, err)
+	assert.Equal(t, ConnectToRule{ToHost: "backend"}, rule)
+
+	_, err = ParseConnectTo("example.com:443:backend")
+	assert.Error(t, err)
+	_, err = ParseConnectTo("example.com:443:backend:99999")
+	assert.ErrorContains(t, err, `invalid port "99999"`)
+}
+
+// tlsClient trusts the test server's certificate, which is valid for
+// example.com, and applies opts on top.
+func tlsClient(srv *httptest.Server, opts ...ClientOption) *Client {
+	c := NewClient(opts...)
+	c.client.Transport.(*http.Transport).TLSClientConfig = srv.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	return c
+}
+
+func TestClient_Resolve(t *testing.T) {
+	var gotHost string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost = r.Host
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	rule, err := ParseResolve("example.com:" + u.Port() + ":127.0.0.1")
+	require.NoError(t, err)
+
+	req, err := NewRequest("https://example.com:" + u.Port())
+	require.NoError(t, err)
+	resp, err := tlsClient(srv, WithResolve(rule)).Send(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.ToHTTP().StatusCode)
+	assert.Equal(t, "example.com:"+u.Port(), gotHost)
+}
+
+func TestClient_ConnectTo(t *testing.T) {
+	var gotHost string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost = r.Host
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	rule, err := ParseConnectTo("example.com:443:127.0.0.1:" + u.Port())
+	require.NoError(t, err)
+
+	req, err := NewRequest("https://example.com/")
+	require.NoError(t, err)
+	resp, err := tlsClient(srv, WithConnectTo(rule)).Send(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.ToHTTP().StatusCode)
+	assert.Equal(t, "example.com", gotHost)
+}
+
+func TestClient_IPVersion(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	// the listener is on 127.0.0.1, so only the IPv4 address can work
+	rule, err := ParseResolve("ghostman.test:*:::1,127.0.0.1")
+	require.NoError(t, err)
+
+	req, err := NewRequest("http://ghostman.test:" + u.Port())
+	require.NoError(t, err)
+	resp, err := NewClient(WithResolve(rule), WithIPVersion(4)).Send(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.ToHTTP().StatusCode)
+
+	req, err = NewRequest("http://ghostman.test:" + u.Port())
+	require.NoError(t, err)
+	rule.Addrs = []string{"127.0.0.1"}
+	_, err = NewClient(WithResolve(rule), WithIPVersion(6)).Send(context.Background(), req)
+	assert.ErrorContains(t, err, "no IPv6 address for ghostman.test")
+}
+
+// dnsServer answers every A query with 127.0.0.1 and counts the queries.
+func dnsServer(t *testing.T) (addr string, queries *atomic.Int32) {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	queries = new(atomic.Int32)
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			var msg dnsmessage.Message
+			if err := msg.Unpack(buf[:n]); err != nil || len(msg.Questions) == 0 {
+				continue
+			}
+
+			q := msg.Questions[0]
+			msg.Header.Response = true
+			msg.Header.Authoritative = true
+			if q.Type == dnsmessage.TypeA {
+				queries.Add(1)
+				msg.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: q.Class, TTL: 60},
+					Body:   &dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}},
+				}}
+			}
+
+			out, err := msg.Pack()
+			if err == nil {
+				conn.WriteTo(out, from)
+			}
+		}
+	}()
+
+	return conn.LocalAddr().String(), queries
+}
+
+func TestClient_DNSServers(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	addr, queries := dnsServer(t)
+
+	req, err := NewRequest("http://ghostman.test:" + u.Port())
+	require.NoError(t, err)
+	resp, err := NewClient(WithDNSServers(addr), WithIPVersion(4)).Send(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.ToHTTP().StatusCode)
+	assert.NotZero(t, queries.Load())
+}
+
+func TestClient_UnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.sock")
+	ln, err := net.Listen("unix", path)
+	require.NoError(t, err)
+
+	var gotHost, gotPath string
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost, gotPath = r.Host, r.URL.Path
+		w.Write([]byte("[]"))
+	})}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	// a proxy from the environment must not get in the way
+	t.Setenv("HTTP_PROXY", "http://127.0.0.1:1")
+
+	req, err := NewRequest("http://docker/v1.43/containers/json")
+	require.NoError(t, err)
+	resp, err := NewClient(WithUnixSocket(path)).Send(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "[]", string(resp.Body()))
+	assert.Equal(t, "docker", gotHost)
+	assert.Equal(t, "/v1.43/containers/json", gotPath)
+	assert.Equal(t, path, resp.UnixSocket())
+	assert.Nil(t, resp.Proxy())
+
+	resp.ShowProxy(true)
+	str, err := resp.ToString()
+	require.NoError(t, err)
+	assert.Contains(t, str, "Unix socket: "+path)
+}
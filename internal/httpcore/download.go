@@ -0,0 +1,308 @@
+package httpcore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ResumeSuffix names the file kept next to an unfinished download. it
+// remembers which version of the resource the partial file holds.
+const ResumeSuffix = ".resume"
+
+// ResumeState is what a download needs to be continued later with a Range
+// request that only succeeds if the resource is still the same.
+type ResumeState struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	// Size is the size of the whole body, -1 when unknown
+	Size int64 `json:"size"`
+}
+
+// NewResumeState records the validators and the full size of resp, a 200
+// or 206 answer to a request for rawURL.
+func NewResumeState(rawURL string, resp *http.Response) ResumeState {
+	s := ResumeState{
+		URL:          rawURL,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Size:         resp.ContentLength,
+	}
+	if resp.StatusCode == http.StatusPartialContent {
+		s.Size = -1
+		if cr, err := ParseContentRange(resp.Header.Get("Content-Range")); err == nil {
+			s.Size = cr.Size
+		}
+	}
+	return s
+}
+
+// LoadResumeState reads the state saved for the download into path, nil if
+// there is none.
+func LoadResumeState(path string) (*ResumeState, error) {
+	data, err := os.ReadFile(path + ResumeSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading resume state: %w", err)
+	}
+
+	var s ResumeState
+	if err = json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("malformed resume state %s: %w", path+ResumeSuffix, err)
+	}
+	return &s, nil
+}
+
+// Save writes the state next to the download into path.
+func (s ResumeState) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding resume state: %w", err)
+	}
+	if err = os.WriteFile(path+ResumeSuffix, data, 0o644); err != nil {
+		return fmt.Errorf("saving resume state: %w", err)
+	}
+	return nil
+}
+
+// RemoveResumeState drops the state of a finished download into path.
+func RemoveResumeState(path string) error {
+	err := os.Remove(path + ResumeSuffix)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing resume state: %w", err)
+	}
+	return nil
+}
+
+// Validator is what If-Range compares with the resource: the ETag unless
+// it's weak, which If-Range doesn't allow, or else Last-Modified.
+func (s ResumeState) Validator() string {
+	if s.ETag != "" && !strings.HasPrefix(s.ETag, "W/") {
+		return s.ETag
+	}
+	return s.LastModified
+}
+
+// SetRange asks for the body from offset on. with a validator, an ETag or a
+// Last-Modified date, the server sends the whole body instead if the
+// resource changed.
+func (r *RequestConf) SetRange(offset int64, validator string) {
+	setRange(r.req, offset, -1, validator)
+}
+
+// setRange asks for the bytes from first to last, to the end if last is
+// negative.
+func setRange(r *http.Request, first, last int64, validator string) {
+	if last < 0 {
+		r.Header.Set("Range", fmt.Sprintf("bytes=%d-", first))
+	} else {
+		r.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", first, last))
+	}
+	if validator != "" {
+		r.Header.Set("If-Range", validator)
+	} else {
+		r.Header.Del("If-Range")
+	}
+}
+
+// ContentRange is a "bytes first-last/size" header. Size is -1 for "*".
+type ContentRange struct {
+	First, Last, Size int64
+}
+
+// ParseContentRange reads a Content-Range header. an unsatisfied range,
+// "bytes */size", comes back with First and Last set to -1.
+func ParseContentRange(v string) (ContentRange, error) {
+	cr := ContentRange{First: -1, Last: -1, Size: -1}
+	invalid := fmt.Errorf("invalid Content-Range %q", v)
+
+	spec, ok := strings.CutPrefix(strings.TrimSpace(v), "bytes ")
+	if !ok {
+		return cr, invalid
+	}
+	rng, size, ok := strings.Cut(spec, "/")
+	if !ok {
+		return cr, invalid
+	}
+
+	var err error
+	if size != "*" {
+		if cr.Size, err = strconv.ParseInt(size, 10, 64); err != nil || cr.Size < 0 {
+			return cr, invalid
+		}
+	}
+	if rng == "*" {
+		return cr, nil
+	}
+
+	first, last, ok := strings.Cut(rng, "-")
+	if !ok {
+		return cr, invalid
+	}
+	if cr.First, err = strconv.ParseInt(first, 10, 64); err != nil {
+		return cr, invalid
+	}
+	if cr.Last, err = strconv.ParseInt(last, 10, 64); err != nil || cr.Last < cr.First {
+		return cr, invalid
+	}
+	if cr.Size >= 0 && cr.Last >= cr.Size {
+		return cr, invalid
+	}
+	return cr, nil
+}
+
+// Segmentable tells whether the body can be fetched in parallel ranges: an
+// unread 200 of a known size from a server that accepts byte ranges, and
+// without a content coding, since ranges count encoded bytes.
+func (r *Response) Segmentable() bool {
+	return !r.consumed &&
+		r.resp.StatusCode == http.StatusOK &&
+		r.resp.ContentLength > 0 &&
+		strings.EqualFold(r.resp.Header.Get("Accept-Ranges"), "bytes") &&
+		r.resp.Header.Get("Content-Encoding") == "" &&
+		(r.final.Body == nil || r.final.Body == http.NoBody)
+}
+
+// DownloadSegments writes the body of resp to f over n connections. resp
+// delivers the first segment, the others are asked for with Range requests
+// that fail if the resource changes meanwhile. if a segment fails, f is cut
+// to the part that arrived in one piece, so it can be resumed.
+func (c *Client) DownloadSegments(ctx context.Context, resp *Response, f *os.File, n int) error {
+	if resp.consumed {
+		return fmt.Errorf("response body was already read or closed")
+	}
+	if !resp.Segmentable() {
+		return fmt.Errorf("the response can't be downloaded in segments")
+	}
+	size := resp.resp.ContentLength
+	n = int(min(int64(max(n, 1)), size))
+
+	if err := f.Truncate(size); err != nil {
+		return fmt.Errorf("allocating %s: %w", f.Name(), err)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	// progress is reported from every segment, one at a time
+	var mu sync.Mutex
+	var received atomic.Int64
+	report := func(delta int64) {
+		total := received.Add(delta)
+		if resp.progress != nil {
+			mu.Lock()
+			resp.progress(total, size)
+			mu.Unlock()
+		}
+	}
+
+	validator := NewResumeState("", resp.resp).Validator()
+	segLen := (size + int64(n) - 1) / int64(n)
+	// rounding up may leave nothing for the last ones
+	n = int((size + segLen - 1) / segLen)
+	done := make([]atomic.Int64, n)
+
+	var wg sync.WaitGroup
+	for i := range n {
+		first := int64(i) * segLen
+		last := min(first+segLen, size) - 1
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := c.downloadSegment(ctx, resp, i, first, last, validator, f, func(k int64) {
+				done[i].Add(k)
+				report(k)
+			})
+			if err != nil {
+				// the first failure stops the others and is the one reported
+				cancel(fmt.Errorf("segment %d of %d: %w", i+1, n, err))
+			}
+		}()
+	}
+	wg.Wait()
+
+	now := received.Load()
+	resp.finish(now, now)
+
+	if err := context.Cause(ctx); err != nil {
+		// keep what can be resumed, the bytes before the first gap
+		var complete int64
+		for i := range n {
+			complete += done[i].Load()
+			if complete < min(int64(i+1)*segLen, size) {
+				break
+			}
+		}
+		if terr := f.Truncate(complete); terr != nil {
+			return errors.Join(err, fmt.Errorf("truncating %s: %w", f.Name(), terr))
+		}
+		return err
+	}
+
+	return nil
+}
+
+// downloadSegment copies the bytes from first to last into f. the first
+// segment comes from resp itself, the others from a new range request.
+func (c *Client) downloadSegment(ctx context.Context, resp *Response, i int, first, last int64, validator string, f *os.File, written func(int64)) error {
+	w := &countingWriter{w: io.NewOffsetWriter(f, first), add: written}
+	want := last - first + 1
+
+	if i == 0 {
+		defer resp.Close()
+		stop := context.AfterFunc(ctx, func() { resp.resp.Body.Close() })
+		defer stop()
+
+		p := &previewWriter{limit: PreviewSize}
+		_, err := io.CopyN(io.MultiWriter(w, p), resp.resp.Body, want)
+		resp.body = p.buf
+		return err
+	}
+
+	r := resp.final.Clone(ctx)
+	setRange(r, first, last, validator)
+
+	ex, err := c.exchange(ctx, r, resp.cookies)
+	if err != nil {
+		return err
+	}
+	defer ex.resp.Body.Close()
+
+	if ex.resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("expected 206 Partial Content, got %s; the resource may have changed", ex.resp.Status)
+	}
+	cr, err := ParseContentRange(ex.resp.Header.Get("Content-Range"))
+	if err != nil {
+		return err
+	}
+	if cr.First != first || cr.Last != last || cr.Size != resp.resp.ContentLength {
+		return fmt.Errorf("asked for bytes %d-%d/%d, got %d-%d/%d", first, last, resp.resp.ContentLength, cr.First, cr.Last, cr.Size)
+	}
+
+	_, err = io.CopyN(w, ex.resp.Body, want)
+	return err
+}
+
+type countingWriter struct {
+	w   io.Writer
+	add func(int64)
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.add(int64(n))
+	return n, err
+}
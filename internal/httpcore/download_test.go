@@ -0,0 +1,158 @@
+package httpcore
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseContentRange(t *testing.T) {
+	cr, err := ParseContentRange("bytes 100-199/1000")
+	require.NoError(t, err)
+	assert.Equal(t, ContentRange{First: 100, Last: 199, Size: 1000}, cr)
+
+	cr, err = ParseContentRange("bytes 0-9/*")
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), cr.Size)
+
+	cr, err = ParseContentRange("bytes */1000")
+	require.NoError(t, err)
+	assert.Equal(t, ContentRange{First: -1, Last: -1, Size: 1000}, cr)
+
+	for _, bad := range []string{"", "bytes 10-5/100", "bytes 0-100/100", "items 0-1/2", "bytes 0-1"} {
+		_, err = ParseContentRange(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestResumeState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.sql")
+
+	state, err := LoadResumeState(path)
+	require.NoError(t, err)
+	assert.Nil(t, state)
+
+	resp := &http.Response{
+		StatusCode: http.StatusPartialContent,
+		Header: http.Header{
+			"Etag":          {`W/"weak"`},
+			"Last-Modified": {"Wed, 01 Jan 2025 12:00:00 GMT"},
+			"Content-Range": {"bytes 10-19/20"},
+		},
+		ContentLength: 10,
+	}
+	saved := NewResumeState("https://example.com/dump.sql", resp)
+	assert.Equal(t, int64(20), saved.Size)
+	// weak ETags can't be used with If-Range
+	assert.Equal(t, "Wed, 01 Jan 2025 12:00:00 GMT", saved.Validator())
+
+	require.NoError(t, saved.Save(path))
+	state, err = LoadResumeState(path)
+	require.NoError(t, err)
+	assert.Equal(t, saved, *state)
+
+	require.NoError(t, RemoveResumeState(path))
+	require.NoError(t, RemoveResumeState(path))
+	_, err = os.Stat(path + ResumeSuffix)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRequestConf_SetRange(t *testing.T) {
+	data := []byte(strings.Repeat("ghostman", 100))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v2"`)
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(data))
+	}))
+	defer srv.Close()
+
+	send := func(validator string) *Response {
+		req, err := NewRequest(srv.URL)
+		require.NoError(t, err)
+		req.SetRange(500, validator)
+		resp, err := NewClient().Send(context.Background(), req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := send(`"v2"`)
+	assert.Equal(t, http.StatusPartialContent, resp.ToHTTP().StatusCode)
+	assert.Equal(t, data[500:], resp.Body())
+
+	// a changed resource comes back whole
+	resp = send(`"v1"`)
+	assert.Equal(t, http.StatusOK, resp.ToHTTP().StatusCode)
+	assert.Equal(t, data, resp.Body())
+}
+
+func TestClient_DownloadSegments(t *testing.T) {
+	data := make([]byte, 1<<20+7)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+
+	// the first segment is all the preview holds
+	segLen := (len(data) + 3) / 4
+
+	var failFrom string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failFrom != "" && strings.HasPrefix(r.Header.Get("Range"), "bytes="+failFrom) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(data))
+	}))
+	defer srv.Close()
+
+	download := func() (*os.File, error) {
+		f, err := os.Create(filepath.Join(t.TempDir(), "data.bin"))
+		require.NoError(t, err)
+		t.Cleanup(func() { f.Close() })
+
+		req, err := NewRequest(srv.URL)
+		require.NoError(t, err)
+
+		client := NewClient()
+		resp, err := client.Stream(context.Background(), req)
+		require.NoError(t, err)
+		require.True(t, resp.Segmentable())
+
+		var received int64
+		resp.OnProgress(func(n, total int64) { received = n })
+
+		err = client.DownloadSegments(context.Background(), resp, f, 4)
+		if err == nil {
+			assert.Equal(t, int64(len(data)), received)
+			assert.Equal(t, int64(len(data)), resp.Size())
+			assert.Equal(t, data[:segLen], resp.Body())
+		}
+		return f, err
+	}
+
+	f, err := download()
+	require.NoError(t, err)
+	got, err := os.ReadFile(f.Name())
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+
+	// the third segment fails. at most the first two can be kept, fewer if
+	// the failure stopped them before they were done
+	failFrom = strconv.Itoa(2 * segLen)
+	f, err = download()
+	assert.ErrorContains(t, err, "segment 3 of 4")
+	got, err = os.ReadFile(f.Name())
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(got), 2*segLen)
+	assert.Zero(t, len(got)%segLen)
+	assert.Equal(t, data[:len(got)], got)
+}
@@ -0,0 +1,134 @@
+package httpcore
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the Netscape cookies.txt format, as written by curl, wget and browser
+// extensions: one cookie per line with seven tab separated fields.
+const (
+	netscapeHeader   = "# Netscape HTTP Cookie File"
+	httpOnlyPrefix   = "#HttpOnly_"
+	netscapeFieldNum = 7
+)
+
+// IsNetscapeCookies reports whether the data looks like a cookies.txt file
+// rather than a JSON jar.
+func IsNetscapeCookies(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return false
+	}
+	return data[0] != '[' && data[0] != '{'
+}
+
+func isNetscapePath(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".txt")
+}
+
+// ParseNetscapeCookies reads every cookie of a cookies.txt file. lines with a
+// bad expiry or field count are errors, since a half-read session is worse
+// than none.
+func ParseNetscapeCookies(r io.Reader) ([]JarCookie, error) {
+	var cookies []JarCookie
+	now := time.Now()
+
+	sc := bufio.NewScanner(r)
+	n := 0
+	for sc.Scan() {
+		n++
+		line := strings.TrimRight(sc.Text(), "\r\n")
+
+		httpOnly := false
+		if strings.HasPrefix(line, httpOnlyPrefix) {
+			httpOnly = true
+			line = strings.TrimPrefix(line, httpOnlyPrefix)
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) == netscapeFieldNum-1 {
+			// some exporters drop the tab before an empty value
+			fields = append(fields, "")
+		}
+		if len(fields) != netscapeFieldNum {
+			return nil, fmt.Errorf("line %d: expected %d tab separated fields, got %d", n, netscapeFieldNum, len(fields))
+		}
+
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid expiry %q", n, fields[4])
+		}
+
+		c := JarCookie{
+			Cookie: Cookie{
+				Domain:   strings.ToLower(strings.TrimPrefix(fields[0], ".")),
+				Path:     fields[2],
+				Secure:   strings.EqualFold(fields[3], "TRUE"),
+				Name:     fields[5],
+				Value:    fields[6],
+				HttpOnly: httpOnly,
+			},
+			HostOnly: !strings.EqualFold(fields[1], "TRUE"),
+			// keep the file order for cookies of the same path
+			Created: now.Add(time.Duration(n) * time.Nanosecond),
+		}
+		if expires > 0 {
+			c.Expires = CookieTime{time.Unix(expires, 0).UTC()}
+		}
+		if c.Path == "" {
+			c.Path = "/"
+		}
+
+		cookies = append(cookies, c)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("reading cookies.txt: %w", err)
+	}
+
+	return cookies, nil
+}
+
+// WriteNetscapeCookies writes cookies in the cookies.txt format, session
+// cookies with an expiry of 0 like curl does.
+func WriteNetscapeCookies(w io.Writer, cookies []JarCookie) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "%s\n# This file was generated by ghostman. Edit at your own risk.\n\n", netscapeHeader)
+
+	for _, c := range cookies {
+		domain := c.Domain
+		if !c.HostOnly {
+			domain = "." + domain
+		}
+		if c.HttpOnly {
+			domain = httpOnlyPrefix + domain
+		}
+
+		var expires int64
+		if !c.Expires.IsZero() {
+			expires = c.Expires.Unix()
+		}
+
+		fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain, netscapeBool(!c.HostOnly), c.Path, netscapeBool(c.Secure), expires, c.Name, c.Value)
+	}
+
+	return bw.Flush()
+}
+
+func netscapeBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
@@ -0,0 +1,92 @@
+package httpcore
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// HTTP versions for Settings.HTTPVersion. HTTP/1.1 is the default, HTTP2
+// negotiates h2 over TLS with ALPN and falls back to HTTP/1.1, and
+// HTTP2PriorKnowledge speaks h2 right away, cleartext h2c included.
+const (
+	HTTP11              = "1.1"
+	HTTP2               = "2"
+	HTTP2PriorKnowledge = "2-prior-knowledge"
+)
+
+func protocols(version string) (http.Protocols, error) {
+	var p http.Protocols
+	switch version {
+	case HTTP11:
+		p.SetHTTP1(true)
+	case HTTP2:
+		p.SetHTTP1(true)
+		p.SetHTTP2(true)
+	case HTTP2PriorKnowledge:
+		p.SetHTTP2(true)
+		p.SetUnencryptedHTTP2(true)
+	default:
+		return p, fmt.Errorf("unknown HTTP version %q, use %s, %s or %s", version, HTTP11, HTTP2, HTTP2PriorKnowledge)
+	}
+	return p, nil
+}
+
+// ExpectedProto is the protocol a request to u will be sent with, as far as
+// it's known before sending: with HTTP2 the server may still pick HTTP/1.1.
+func (s Settings) ExpectedProto(u *url.URL) string {
+	switch {
+	case s.HTTPVersion == HTTP2PriorKnowledge,
+		s.HTTPVersion == HTTP2 && u.Scheme == "https":
+		return "HTTP/2.0"
+	default:
+		return "HTTP/1.1"
+	}
+}
+
+// SetProto sets the protocol the request is shown with, e.g. "HTTP/2.0".
+// the transport decides what is actually sent.
+func (r *RequestConf) SetProto(proto string) error {
+	major, minor, ok := http.ParseHTTPVersion(proto)
+	if !ok {
+		return fmt.Errorf("invalid protocol %q", proto)
+	}
+	r.req.Proto, r.req.ProtoMajor, r.req.ProtoMinor = proto, major, minor
+	return nil
+}
+
+// headers HTTP/2 doesn't allow, the connection handles what they did
+var connectionHeaders = map[string]bool{
+	"host": true, "connection": true, "keep-alive": true, "proxy-connection": true,
+	"transfer-encoding": true, "upgrade": true,
+}
+
+// h2Headers turns the header lines of an HTTP/1.1 dump into what goes over
+// HTTP/2: pseudo-headers first, lowercase names, no connection headers.
+func h2Headers(r *http.Request, lines []string) []string {
+	headers := []string{
+		":method: " + r.Method,
+		":scheme: " + r.URL.Scheme,
+		":authority: " + r.URL.Host,
+		":path: " + r.URL.RequestURI(),
+	}
+
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+		if connectionHeaders[name] {
+			continue
+		}
+		if name == "user-agent" && value == "Go-http-client/1.1" {
+			value = "Go-http-client/2.0"
+		}
+		headers = append(headers, name+": "+value)
+	}
+
+	return headers
+}
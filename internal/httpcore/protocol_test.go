@@ -0,0 +1,84 @@
+package httpcore
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_HTTPVersion(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	})
+
+	tlsSrv := httptest.NewUnstartedServer(handler)
+	tlsSrv.EnableHTTP2 = true
+	tlsSrv.StartTLS()
+	defer tlsSrv.Close()
+
+	h2c := httptest.NewUnstartedServer(handler)
+	h2c.Config.Protocols = new(http.Protocols)
+	h2c.Config.Protocols.SetHTTP1(true)
+	h2c.Config.Protocols.SetUnencryptedHTTP2(true)
+	h2c.Start()
+	defer h2c.Close()
+
+	insecure := true
+	testcases := []struct {
+		Name     string
+		URL      string
+		Version  string
+		Expected string
+	}{
+		{"default is HTTP/1.1", tlsSrv.URL, "", "HTTP/1.1"},
+		{"forced HTTP/1.1", tlsSrv.URL, HTTP11, "HTTP/1.1"},
+		{"h2 negotiated over TLS", tlsSrv.URL, HTTP2, "HTTP/2.0"},
+		{"h2 over TLS with prior knowledge", tlsSrv.URL, HTTP2PriorKnowledge, "HTTP/2.0"},
+		{"cleartext stays HTTP/1.1 with --http2", h2c.URL, HTTP2, "HTTP/1.1"},
+		{"h2c with prior knowledge", h2c.URL, HTTP2PriorKnowledge, "HTTP/2.0"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			opts, err := Settings{HTTPVersion: tc.Version, Insecure: &insecure}.ClientOptions()
+			require.NoError(t, err)
+
+			req, err := NewRequest(tc.URL)
+			require.NoError(t, err)
+
+			resp, err := NewClient(opts...).Send(context.Background(), req)
+			require.NoError(t, err)
+			assert.Equal(t, tc.Expected, resp.ToHTTP().Proto)
+			assert.Equal(t, tc.Expected, string(resp.Body()))
+		})
+	}
+
+	_, err := Settings{HTTPVersion: "3"}.ClientOptions()
+	assert.ErrorContains(t, err, "unknown HTTP version")
+}
+
+func TestRequestConf_ToString_HTTP2(t *testing.T) {
+	req, err := NewRequest("https://example.com/users?page=2")
+	require.NoError(t, err)
+	req.ToHTTP().Header.Set("X-Trace", "abc")
+
+	str, err := req.ToString()
+	require.NoError(t, err)
+	assert.Contains(t, str, "HTTP/1.1")
+	assert.Contains(t, str, "Host: example.com")
+
+	proto := Settings{HTTPVersion: HTTP2}.ExpectedProto(req.ToHTTP().URL)
+	require.NoError(t, req.SetProto(proto))
+
+	str, err = req.ToString()
+	require.NoError(t, err)
+	assert.Contains(t, str, "HTTP/2.0")
+	assert.Contains(t, str, ":authority: example.com")
+	assert.Contains(t, str, ":path: /users?page=2")
+	assert.Contains(t, str, "x-trace: abc")
+	assert.NotContains(t, str, "Host:")
+}
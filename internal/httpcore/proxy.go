@@ -0,0 +1,119 @@
+package httpcore
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// ParseProxyURL accepts http://, https://, socks5:// and socks5h:// proxies,
+// with credentials as user:password@host. without a scheme http:// is
+// assumed, like curl does.
+func ParseProxyURL(s string) (*url.URL, error) {
+	if !strings.Contains(s, "://") {
+		s = "http://" + s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, use http, https or socks5", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy URL %s has no host", u.Redacted())
+	}
+
+	return u, nil
+}
+
+// ProxyFunc picks the proxy for each request. without an explicit proxy it
+// reads HTTP_PROXY, HTTPS_PROXY and NO_PROXY, with NoProxy replacing the
+// latter. it returns nil when neither Proxy nor NoProxy is set, leaving the
+// client default in place.
+func (s Settings) ProxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	if s.Proxy == "" {
+		if s.NoProxy == "" {
+			return nil, nil
+		}
+
+		cfg := httpproxy.FromEnvironment()
+		cfg.NoProxy = s.NoProxy
+		proxy := cfg.ProxyFunc()
+		return func(r *http.Request) (*url.URL, error) {
+			return proxy(r.URL)
+		}, nil
+	}
+
+	u, err := ParseProxyURL(s.Proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(r *http.Request) (*url.URL, error) {
+		if noProxy(r.URL.Hostname(), s.NoProxy) {
+			return nil, nil
+		}
+		return u, nil
+	}, nil
+}
+
+// noProxy reports whether host is in the comma separated list, the way curl
+// reads --noproxy: "*" matches everything, a domain matches itself and its
+// subdomains, and IPs may be given as CIDR ranges.
+func noProxy(host, list string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	ip := net.ParseIP(host)
+
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+
+		if ip != nil {
+			if _, cidr, err := net.ParseCIDR(entry); err == nil {
+				if cidr.Contains(ip) {
+					return true
+				}
+				continue
+			}
+			if other := net.ParseIP(entry); other != nil && other.Equal(ip) {
+				return true
+			}
+			continue
+		}
+
+		entry = strings.TrimSuffix(strings.TrimPrefix(entry, "."), ".")
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// proxyFor tells which proxy the client's transport picks for r, nil for a
+// direct connection or a replaced transport.
+func (c *Client) proxyFor(r *http.Request) *url.URL {
+	if c.client.Transport != c.transport || c.transport.Proxy == nil {
+		return nil
+	}
+
+	u, err := c.transport.Proxy(r)
+	if err != nil {
+		return nil
+	}
+	return u
+}
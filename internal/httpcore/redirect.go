@@ -0,0 +1,168 @@
+package httpcore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultMaxRedirects is the limit browsers use as well.
+const DefaultMaxRedirects = 20
+
+// Redirect is one hop of a followed redirect chain.
+type Redirect struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Location   string
+	SetCookies []string
+}
+
+// Hop is one request sent for a Response and the response it got. only the
+// head of the response is kept for redirects and retried attempts, their
+// bodies were discarded.
+type Hop struct {
+	Request  *http.Request
+	Response *http.Response
+	Started  time.Time
+	Timing   *Timing
+}
+
+func newHop(r *http.Request, resp *http.Response, trace *tracer) Hop {
+	return Hop{Request: r, Response: resp, Started: trace.start, Timing: trace.timing(time.Now())}
+}
+
+// headers that carry credentials and must not leak to another host
+var credentialHeaders = []string{"Authorization", "Cookie"}
+
+// nextRequest builds the request for the redirect in resp, or returns nil if
+// resp is final. 301 and 302 turn POST into GET and 303 turns everything
+// but HEAD into GET, dropping the body. 307 and 308 replay method and body.
+// user holds the cookies the user set, the jar ones are looked up again for
+// every hop. the user cookies kept for the next hop are returned with it.
+func (c *Client) nextRequest(ctx context.Context, r *http.Request, resp *http.Response, hops int, user []*http.Cookie) (*http.Request, []*http.Cookie, error) {
+	if c.maxRedirects <= 0 {
+		return nil, nil, nil
+	}
+
+	switch resp.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return nil, nil, nil
+	}
+
+	loc := resp.Header.Get("Location")
+	if loc == "" {
+		return nil, nil, nil
+	}
+
+	if hops >= c.maxRedirects {
+		return nil, nil, fmt.Errorf("stopped after %d redirects", c.maxRedirects)
+	}
+
+	u, err := r.URL.Parse(loc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid Location %q: %w", loc, err)
+	}
+
+	method, keepBody := r.Method, true
+	switch resp.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound:
+		if method == http.MethodPost {
+			method, keepBody = http.MethodGet, false
+		}
+	case http.StatusSeeOther:
+		if method != http.MethodHead {
+			method = http.MethodGet
+		}
+		keepBody = false
+	}
+
+	next, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("building redirect request: %w", err)
+	}
+	next.Header = r.Header.Clone()
+
+	hasBody := r.Body != nil && r.Body != http.NoBody
+	switch {
+	case keepBody && hasBody:
+		if r.GetBody == nil {
+			return nil, nil, fmt.Errorf("can't replay the request body for a %d redirect", resp.StatusCode)
+		}
+		next.Body, err = r.GetBody()
+		if err != nil {
+			return nil, nil, fmt.Errorf("replaying request body: %w", err)
+		}
+		next.GetBody, next.ContentLength = r.GetBody, r.ContentLength
+	case hasBody:
+		next.Header.Del("Content-Type")
+		next.Header.Del("Content-Length")
+	}
+
+	if !sameHost(r.URL, u) && !c.locationTrusted {
+		for _, h := range credentialHeaders {
+			next.Header.Del(h)
+		}
+		user = nil
+	}
+
+	c.setCookies(next, user)
+
+	return next, user, nil
+}
+
+// setCookies replaces the Cookie header of r with the user cookies and the
+// ones the jar holds for r.URL at this point. the previous hop may have
+// changed, expired or moved them out of the path. without a jar r is kept.
+func (c *Client) setCookies(r *http.Request, user []*http.Cookie) {
+	if c.jar == nil {
+		return
+	}
+
+	r.Header.Del("Cookie")
+	for _, ck := range user {
+		r.AddCookie(ck)
+	}
+	c.jar.attach(r)
+}
+
+func newRedirect(r *http.Request, resp *http.Response) Redirect {
+	return Redirect{
+		Method:     r.Method,
+		URL:        r.URL.String(),
+		StatusCode: resp.StatusCode,
+		Location:   resp.Header.Get("Location"),
+		SetCookies: resp.Header.Values("Set-Cookie"),
+	}
+}
+
+// drain reads a little of an intermediate body so the connection can be
+// reused, then closes it.
+func drain(body io.ReadCloser) {
+	io.Copy(io.Discard, io.LimitReader(body, 64*1024))
+	body.Close()
+}
+
+func sameHost(a, b *url.URL) bool {
+	return strings.EqualFold(hostPort(a), hostPort(b))
+}
+
+func hostPort(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "https":
+			port = "443"
+		default:
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
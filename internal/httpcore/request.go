@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss/tree"
@@ -28,7 +29,16 @@ func NewRequest(reqUrl string) (*RequestConf, error) {
 }
 
 func NewRequestFromJSON(j []byte) (req *RequestConf, err error) {
-	ser := RequestSerializable{
+	ser, err := DecodeRequest(j)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRequestFromSerializable(ser)
+}
+
+func DecodeRequest(j []byte) (ser RequestSerializable, err error) {
+	ser = RequestSerializable{
 		Method:      http.MethodGet,
 		QueryParams: make(map[string][]string),
 		Headers:     make(map[string][]string),
@@ -39,16 +49,25 @@ func NewRequestFromJSON(j []byte) (req *RequestConf, err error) {
 	dec.DisallowUnknownFields()
 
 	if err = dec.Decode(&ser); err != nil {
-		return nil, fmt.Errorf("error reading request config: %w", err)
+		return ser, fmt.Errorf("error reading request config: %w", err)
+	}
+
+	return ser, nil
+}
+
+func NewRequestFromSerializable(ser RequestSerializable) (req *RequestConf, err error) {
+	if ser.Method == "" {
+		ser.Method = http.MethodGet
 	}
 
 	var buf []byte
+	var ct string
 	if ser.Body != nil {
-		buf, err = ser.Body.Parse()
+		buf, ct, err = ser.Body.Parse()
 		if err != nil {
 			return nil, fmt.Errorf("error parsing body: %w", err)
 		}
-	} 
+	}
 
 	request, err := http.NewRequest(ser.Method, ser.URL, bytes.NewReader(buf))
 	if err != nil {
@@ -79,16 +98,37 @@ func NewRequestFromJSON(j []byte) (req *RequestConf, err error) {
 		}
 	}
 
-	if buf != nil {
-		ct := mimetype.Detect(buf)
-		request.Header.Add("Content-Type", ct.String())
+	if ser.Auth != nil {
+		if err = ser.Auth.apply(request); err != nil {
+			return nil, fmt.Errorf("error applying auth: %w", err)
+		}
 	}
 
-	return &RequestConf{req: request}, nil
+	switch {
+	case strings.HasPrefix(ct, "multipart/"):
+		// the body only parses with the boundary it was built with
+		request.Header.Set("Content-Type", ct)
+	case buf != nil && request.Header.Get("Content-Type") == "":
+		if ct == "" {
+			ct = mimetype.Detect(buf).String()
+		}
+		request.Header.Add("Content-Type", ct)
+	}
+
+	conf := &RequestConf{req: request}
+	if ser.Settings != nil {
+		conf.settings = *ser.Settings
+	}
+
+	return conf, nil
 }
 
 type RequestConf struct {
-	req *http.Request
+	req      *http.Request
+	settings Settings
+
+	// names of the cookies CookieJar.Attach added
+	jarCookies []string
 }
 
 func (r RequestConf) ToString() (string, error) {
@@ -123,7 +163,11 @@ func (r RequestConf) ToString() (string, error) {
 		headers = append(headers, row)
 	}
 
-	t := tree.Root(fmt.Sprintf("%s %s", Method(r.req.Method), r.req.URL))
+	if r.req.ProtoMajor == 2 {
+		headers = h2Headers(r.req, headers)
+	}
+
+	t := tree.Root(fmt.Sprintf("%s %s %s", Method(r.req.Method), r.req.URL, r.req.Proto))
 
 	if len(headers) > 0 {
 		h := tree.Root("Headers:")
@@ -164,10 +208,40 @@ func (h RequestConf) GetBody() io.ReadCloser {
 	return h.req.Body
 }
 
+// BodyBytes reads the whole body and puts it back, so the request can still be sent.
+func (r *RequestConf) BodyBytes() ([]byte, error) {
+	if r.req.Body == nil || r.req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	buf, err := io.ReadAll(r.req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading request body: %w", err)
+	}
+	r.req.Body = io.NopCloser(bytes.NewReader(buf))
+
+	return buf, nil
+}
+
+// userCookies returns the request cookies except the ones a jar attached.
+func (r *RequestConf) userCookies() []*http.Cookie {
+	return slices.DeleteFunc(r.req.Cookies(), func(c *http.Cookie) bool {
+		return slices.Contains(r.jarCookies, c.Name)
+	})
+}
+
 func (r *RequestConf) ToHTTP() *http.Request {
 	return r.req
 }
 
+func (r *RequestConf) Settings() Settings {
+	return r.settings
+}
+
+func (r *RequestConf) SetSettings(s Settings) {
+	r.settings = s
+}
+
 // TODO: set, get, del, remove
 func (r *RequestConf) AddQueryParam(key string, vals ...string) {
 	q := r.req.URL.Query()
@@ -196,6 +270,10 @@ func (r *RequestConf) SetBody(buf []byte, ct string) {
 	}
 
 	r.req.Body = io.NopCloser(bytes.NewReader(buf))
+	// lets redirects replay the body
+	r.req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf)), nil
+	}
 
 	if ct == "" {
 		mimeCt := mimetype.Detect(buf)
@@ -213,4 +291,6 @@ type RequestSerializable struct {
 	Headers     map[string][]string `json:"headers,omitempty"`
 	Cookies     []Cookie            `json:"cookies,omitempty"`
 	Body        *BodySpec           `json:"body,omitempty"`
+	Auth        *AuthSpec           `json:"auth,omitempty"`
+	Settings    *Settings           `json:"settings,omitempty"`
 }
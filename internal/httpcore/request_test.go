@@ -1 +1,63 @@
 package httpcore
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRequestFromSerializable_Multipart(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f, h, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer f.Close()
+		fmt.Fprintf(w, "name=%s file=%s", r.FormValue("name"), h.Filename)
+	}))
+	defer srv.Close()
+
+	file := filepath.Join(t.TempDir(), "a.txt")
+	require.NoError(t, os.WriteFile(file, []byte("hello"), 0o644))
+
+	req, err := NewRequestFromSerializable(RequestSerializable{
+		Method: http.MethodPost,
+		URL:    srv.URL,
+		// a Content-Type without a boundary, as some imports carry, is replaced
+		Headers: map[string][]string{"Content-Type": {"multipart/form-data"}},
+		Body: &BodySpec{Type: "multipart", MultipartFields: &[]MultipartField{
+			{Name: "name", Text: "ghost"},
+			{Name: "file", File: file},
+		}},
+	})
+	require.NoError(t, err)
+	assert.Regexp(t, `^multipart/form-data; boundary=\w+$`, req.ToHTTP().Header.Get("Content-Type"))
+
+	resp, err := NewClient().Send(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.ToHTTP().StatusCode, string(resp.Body()))
+	assert.Equal(t, "name=ghost file=a.txt", string(resp.Body()))
+}
+
+func TestNewRequestFromSerializable_Form(t *testing.T) {
+	form := map[string][]string{"a": {"1"}}
+	req, err := NewRequestFromSerializable(RequestSerializable{
+		Method: http.MethodPost,
+		URL:    "http://example.com/",
+		Body:   &BodySpec{Type: "form", FormData: &form},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "application/x-www-form-urlencoded", req.ToHTTP().Header.Get("Content-Type"))
+}
@@ -1,40 +1,330 @@
 package httpcore
 
 import (
-	"bytes"
+	"bufio"
+	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss/tree"
 	"github.com/gabriel-vasile/mimetype"
 )
 
+// PreviewSize is how much of a consumed body a Response keeps for ToString
+// and content type detection.
+const PreviewSize = 1 << 20
+
+// sniffLen is what mimetype looks at to detect a content type.
+const sniffLen = 3072
+
 type Response struct {
 	resp *http.Response
-	body []byte // used for reading after closing the resp.Body
+	// body is the whole body after Send, or its first PreviewSize bytes
+	// after Consume. size counts all of it
+	body []byte
+	size int64
+
+	// the unread body. reader decodes it, if decode holds content codings.
+	// final is the request resp answers, after redirects, and cookies the
+	// user cookies it carried
+	final    *http.Request
+	cookies  []*http.Cookie
+	ctx      context.Context
+	trace    *tracer
+	decode   string
+	reader   *bufio.Reader
+	closers  []func() error
+	consumed bool
+	closed   bool
+	progress func(received, total int64)
+
+	started   time.Time
+	elapsed   time.Duration
+	redirects []Redirect
+	attempts  []Attempt
+	hops      []Hop
+	timing    *Timing
+	proxy     *url.URL
+	socket    string
+	// size of the body as received and its content coding, when the client
+	// decoded it
+	wireSize int64
+	encoding string
+
+	showTiming   bool
+	showTLS      bool
+	showProxy    bool
+	certWarnDays int
+}
+
+func (r *Response) ToHTTP() *http.Response {
+	return r.resp
+}
+
+// Body is the response body, decoded if the client was asked to. after
+// Consume it only holds the first PreviewSize bytes, see Size.
+func (r *Response) Body() []byte {
+	return r.body
+}
+
+// Size is the size of the whole body as read, after decoding.
+func (r *Response) Size() int64 {
+	return r.size
+}
+
+// OnProgress calls fn while the body is read with how much of it arrived so
+// far and its Content-Length, -1 when unknown. both count bytes as received.
+func (r *Response) OnProgress(fn func(received, total int64)) {
+	r.progress = fn
+}
+
+// Consume copies the body to w as it arrives and closes it, keeping the
+// first PreviewSize bytes for Body and ToString.
+func (r *Response) Consume(w io.Writer) error {
+	return r.consume(w, PreviewSize)
+}
+
+// Close drops the body if it wasn't consumed.
+func (r *Response) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed, r.consumed = true, true
+
+	for i := len(r.closers) - 1; i >= 0; i-- {
+		r.closers[i]()
+	}
+	return r.resp.Body.Close()
+}
+
+// consume reads the body into w, keeping up to keep bytes of it, or all of
+// it when keep is negative.
+func (r *Response) consume(w io.Writer, keep int) error {
+	if r.consumed {
+		return fmt.Errorf("response body was already read or closed")
+	}
+	defer r.Close()
+
+	src, err := r.open()
+
+	var n int64
+	if err == nil {
+		p := &previewWriter{limit: keep, buf: r.body[:0]}
+		n, err = io.Copy(io.MultiWriter(w, p), src)
+		r.body = p.buf
+	}
+	r.finish(n, r.wireSize)
+
+	if err != nil {
+		if r.ctx.Err() != nil {
+			return fmt.Errorf("request aborted while reading the body: %w", context.Cause(r.ctx))
+		}
+		return fmt.Errorf("copying response body: %w", err)
+	}
+	return nil
+}
+
+// finish records a body that was read, size bytes after decoding and wire
+// bytes as received.
+func (r *Response) finish(size, wire int64) {
+	r.consumed = true
+	r.size, r.wireSize = size, wire
+
+	done := time.Now()
+	r.elapsed = done.Sub(r.started)
+	r.timing = r.trace.timing(done)
+}
+
+// open sets up reading the body on first use, decoding it if asked to.
+// it's buffered so the content type can be sniffed before consuming.
+func (r *Response) open() (*bufio.Reader, error) {
+	if r.reader != nil {
+		return r.reader, nil
+	}
+
+	total := r.resp.ContentLength
+	wire := bufio.NewReader(&wireReader{r: r.resp.Body, n: &r.wireSize, total: total, progress: &r.progress})
+	r.reader = wire
+
+	// empty bodies, e.g. of HEAD requests, have nothing to decode
+	if _, err := wire.Peek(1); r.decode == "" || err != nil {
+		return r.reader, nil
+	}
+
+	dec, err := newDecoder(wire, r.decode)
+	if err != nil {
+		return nil, err
+	}
+	r.closers = append(r.closers, dec.Close)
+	r.encoding = r.decode
+	r.reader = bufio.NewReaderSize(dec, sniffLen)
+
+	return r.reader, nil
+}
+
+// previewWriter keeps the first limit bytes written to it.
+type previewWriter struct {
+	buf   []byte
+	limit int
+}
+
+func (p *previewWriter) Write(b []byte) (int, error) {
+	keep := b
+	if p.limit >= 0 {
+		keep = b[:min(len(b), max(p.limit-len(p.buf), 0))]
+	}
+	p.buf = append(p.buf, keep...)
+	return len(b), nil
+}
+
+// wireReader counts the body as received and reports progress.
+type wireReader struct {
+	r        io.Reader
+	n        *int64
+	total    int64
+	progress *func(received, total int64)
+}
+
+func (w *wireReader) Read(p []byte) (int, error) {
+	n, err := w.r.Read(p)
+	*w.n += int64(n)
+	if *w.progress != nil {
+		(*w.progress)(*w.n, w.total)
+	}
+	return n, err
 }
 
+// WireSize is the size of the body as it was received, before decoding.
+func (r *Response) WireSize() int64 {
+	return r.wireSize
+}
+
+// Encoding is the Content-Encoding the body was decoded from, empty if it
+// wasn't encoded or wasn't decoded.
+func (r *Response) Encoding() string {
+	return r.encoding
+}
+
+// Decodes tells whether the body is decoded as it's read, so it won't match
+// the Content-Length or byte ranges of the response. unlike Encoding it's
+// known before the body is read.
+func (r *Response) Decodes() bool {
+	return r.decode != ""
+}
+
+// Started is the moment the request was sent.
+func (r *Response) Started() time.Time {
+	return r.started
+}
+
+// Elapsed is the time from sending the request until the whole body was
+// read, zero before that.
+func (r *Response) Elapsed() time.Duration {
+	return r.elapsed
+}
+
+// Timing breaks down the last request, nil until the body was read.
+func (r *Response) Timing() *Timing {
+	return r.timing
+}
+
+// ShowTiming adds the timing waterfall to ToString.
+func (r *Response) ShowTiming(show bool) {
+	r.showTiming = show
+}
+
+// TLS is the state of the connection the final response came over, nil for
+// plain HTTP.
+func (r *Response) TLS() *tls.ConnectionState {
+	return r.resp.TLS
+}
+
+// ShowTLS adds the TLS details and certificate chain to ToString,
+// highlighting certificates that expire within warnDays.
+func (r *Response) ShowTLS(show bool, warnDays int) {
+	r.showTLS = show
+	r.certWarnDays = warnDays
+}
+
+// CertWarnings lists the certificates of the chain that expire within
+// warnDays.
+func (r *Response) CertWarnings(warnDays int) []string {
+	return CertWarnings(r.TLS(), warnDays, time.Now())
+}
+
+// Proxy is the proxy the final request went through, nil for a direct
+// connection.
+func (r *Response) Proxy() *url.URL {
+	return r.proxy
+}
+
+// UnixSocket is the socket the request was sent through, empty when it
+// went over the network.
+func (r *Response) UnixSocket() string {
+	return r.socket
+}
+
+// ShowProxy adds the proxy or Unix socket used to ToString, with the proxy
+// password hidden.
+func (r *Response) ShowProxy(show bool) {
+	r.showProxy = show
+}
+
+// Attempts lists the failed tries that were retried before this response.
+func (r *Response) Attempts() []Attempt {
+	return r.attempts
+}
+
+// URL is where the response came from, after redirects.
+func (r *Response) URL() *url.URL {
+	return r.final.URL
+}
+
+// Hops lists every request sent for this response with the response it got,
+// oldest first: followed redirects, retried attempts and last the final
+// exchange, whose timing is only known once the body was read. attempts that
+// failed without a response are left out.
+func (r *Response) Hops() []Hop {
+	final := Hop{Request: r.final, Response: r.resp, Started: r.trace.start, Timing: r.timing}
+	return append(slices.Clone(r.hops), final)
+}
+
+// Redirects lists the hops followed before this response, oldest first.
+func (r *Response) Redirects() []Redirect {
+	return r.redirects
+}
+
+// ContentType is the Content-Type header, or detected from the body. the
+// start of an unread body is peeked at for that.
 func(r *Response) ContentType() string {
 	ct := r.resp.Header.Get("Content-Type")
 	if ct == "" {
-		mimeCt := mimetype.Detect(r.body)
+		data := r.body
+		if !r.consumed {
+			if br, err := r.open(); err == nil {
+				data, _ = br.Peek(sniffLen)
+			}
+		}
+		mimeCt := mimetype.Detect(data)
 		ct = mimeCt.String()
 	}
 	return ct
 }
 
 func (r Response) ToString() (str string, err error) {
-	dumpBody := false
-	if r.body != nil {
-		rdr := bytes.NewReader(r.body)
-		r.resp.Body = io.NopCloser(rdr)
-		dumpBody = true
-	}
+	// the body is described from r.body, which may be decoded and wouldn't
+	// match Content-Length in a dump. it may also not be read yet
+	head := *r.resp
+	head.Body = http.NoBody
 
 	var dump []byte
-	dump, err = DumpResponse(r.resp, dumpBody)
+	dump, err = DumpResponse(&head, false)
 	if err != nil {
 		return "", fmt.Errorf("dumping response safely: %w", err)
 	}
@@ -59,7 +349,48 @@ func (r Response) ToString() (str string, err error) {
 		headers = append(headers, strings.TrimSpace(part))
 	}
 
-	t := tree.Root(Status(r.resp.StatusCode))
+	t := tree.Root(fmt.Sprintf("%s %s", r.resp.Proto, Status(r.resp.StatusCode)))
+
+	if r.showProxy {
+		if r.socket != "" {
+			t.Child("Unix socket: " + r.socket)
+		} else if r.proxy != nil {
+			t.Child("Proxy: " + r.proxy.Redacted())
+		} else {
+			t.Child("Proxy: none, direct connection")
+		}
+	}
+
+	if len(r.attempts) != 0 {
+		at := tree.Root("Retries:")
+		for _, a := range r.attempts {
+			reason := Status(a.StatusCode).String()
+			if a.Err != nil {
+				reason = a.Err.Error()
+			}
+			wait := "waited " + a.Wait.String()
+			if a.RetryAfter {
+				wait += " (Retry-After)"
+			}
+			at.Child(fmt.Sprintf("#%d %s, %s", a.Number, reason, wait))
+		}
+
+		t.Child(at)
+	}
+
+	if len(r.redirects) != 0 {
+		rt := tree.Root("Redirects:")
+		for _, hop := range r.redirects {
+			h := tree.Root(fmt.Sprintf("%s %s %s", Status(hop.StatusCode), hop.Method, hop.URL))
+			h.Child("Location: " + hop.Location)
+			for _, c := range hop.SetCookies {
+				h.Child("Set-Cookie: " + c)
+			}
+			rt.Child(h)
+		}
+
+		t.Child(rt)
+	}
 
 	if len(headers) != 0 {
 		h := tree.Root("Headers:")
@@ -79,19 +410,28 @@ func (r Response) ToString() (str string, err error) {
 		t.Child(c)
 	}
 
-	bytesIndex := bytes.Index(dump, []byte("\r\n\r\n"))
-	if bytesIndex != -1 && bytesIndex+4 != len(dump) {
-		body := dump[bytesIndex+4:]
-
-		size := FormatBytes(int64(len(body)))
+	if r.size != 0 {
+		size := FormatBytes(r.size)
 
 		ct := r.resp.Header.Get("Content-Type")
 		if ct == "" {
-			mimeCt := mimetype.Detect(body)
+			mimeCt := mimetype.Detect(r.body)
 			ct = mimeCt.String()
 		}
 
-		t.Child(fmt.Sprintf("Body: %s of %s", size, ct))
+		line := fmt.Sprintf("Body: %s of %s", size, ct)
+		if r.encoding != "" {
+			line += fmt.Sprintf(", %s %s on the wire", FormatBytes(r.wireSize), r.encoding)
+		}
+		t.Child(line)
+	}
+
+	if r.showTLS {
+		t.Child(TLSTree(r.resp.TLS, r.certWarnDays, time.Now()))
+	}
+
+	if r.showTiming && r.timing != nil {
+		t.Child(r.timing.Tree())
 	}
 
 	return t.String(), nil
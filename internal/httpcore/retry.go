@@ -0,0 +1,219 @@
+package httpcore
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRetryOn retries what curl treats as transient: connection errors,
+// timeouts and the 408, 429, 500, 502, 503 and 504 responses.
+const DefaultRetryOn = "connect,408,429,500,502,503,504"
+
+// maxBackoff caps the doubling delay between attempts, as curl does.
+const maxBackoff = 10 * time.Minute
+
+// RetryPolicy decides whether a failed request is sent again and when.
+type RetryPolicy struct {
+	// Retries is how many times a request is repeated after the first try
+	Retries int
+	// Delay is a fixed wait between attempts. zero waits a second and
+	// doubles the wait after each attempt
+	Delay time.Duration
+	// MaxTime stops retrying once the next attempt would start that long
+	// after the first one. zero means no limit
+	MaxTime time.Duration
+	On      RetryOn
+}
+
+// RetryOn says which failures are worth another attempt.
+type RetryOn struct {
+	// Connect covers errors before a response arrived, like refused
+	// connections, resets and timeouts, but not certificate failures
+	Connect      bool
+	ClientErrors bool
+	ServerErrors bool
+	Codes        []int
+}
+
+// ParseRetryOn reads a comma separated list of "connect", "4xx", "5xx" and
+// status codes, e.g. "connect,5xx,429".
+func ParseRetryOn(s string) (RetryOn, error) {
+	var on RetryOn
+	for _, token := range strings.Split(s, ",") {
+		token = strings.ToLower(strings.TrimSpace(token))
+		switch token {
+		case "":
+		case "connect":
+			on.Connect = true
+		case "4xx":
+			on.ClientErrors = true
+		case "5xx":
+			on.ServerErrors = true
+		default:
+			code, err := strconv.Atoi(token)
+			if err != nil || code < 100 || code > 999 {
+				return on, fmt.Errorf("invalid retry condition %q, use connect, 4xx, 5xx or a status code", token)
+			}
+			on.Codes = append(on.Codes, code)
+		}
+	}
+	return on, nil
+}
+
+func (on RetryOn) matches(status int) bool {
+	switch {
+	case on.ClientErrors && status >= 400 && status < 500,
+		on.ServerErrors && status >= 500 && status < 600:
+		return true
+	}
+	for _, code := range on.Codes {
+		if code == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Attempt is a try of a request that failed and was retried.
+type Attempt struct {
+	Number int
+	// StatusCode is zero when the attempt failed without a response
+	StatusCode int
+	Err        error
+	// Wait is the pause before the next attempt, RetryAfter tells it was
+	// asked for by the server
+	Wait       time.Duration
+	RetryAfter bool
+}
+
+func (a Attempt) String() string {
+	reason := fmt.Sprintf("%d %s", a.StatusCode, http.StatusText(a.StatusCode))
+	if a.Err != nil {
+		reason = a.Err.Error()
+	}
+
+	wait := fmt.Sprintf("retrying in %s", a.Wait)
+	if a.RetryAfter {
+		wait += " as asked by Retry-After"
+	}
+
+	return fmt.Sprintf("attempt %d failed with %s, %s", a.Number, reason, wait)
+}
+
+// next tells whether attempt n, which ended with resp or err, is retried,
+// returning the attempt with the wait before the next one.
+func (p *RetryPolicy) next(n int, started time.Time, resp *http.Response, err error) (Attempt, bool) {
+	a := Attempt{Number: n, Err: err}
+	if p == nil || n > p.Retries {
+		return a, false
+	}
+
+	if err != nil {
+		var urlErr *url.Error
+		if !p.On.Connect || !errors.As(err, &urlErr) || isCertError(err) {
+			return a, false
+		}
+	} else {
+		a.StatusCode = resp.StatusCode
+		if !p.On.matches(resp.StatusCode) {
+			return a, false
+		}
+	}
+
+	a.Wait = p.backoff(n)
+	if resp != nil {
+		if d, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			a.Wait, a.RetryAfter = d, true
+		}
+	}
+
+	if p.MaxTime > 0 && time.Since(started)+a.Wait > p.MaxTime {
+		return a, false
+	}
+
+	return a, true
+}
+
+// isCertError tells failures that another attempt won't fix: a certificate
+// that doesn't verify, or a server that doesn't speak TLS. curl doesn't
+// retry them either.
+func isCertError(err error) bool {
+	var (
+		verifyErr    *tls.CertificateVerificationError
+		recordErr    tls.RecordHeaderError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	return errors.As(err, &verifyErr) ||
+		errors.As(err, &recordErr) ||
+		errors.As(err, &authorityErr) ||
+		errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidErr)
+}
+
+func (p *RetryPolicy) backoff(n int) time.Duration {
+	if p.Delay > 0 {
+		return p.Delay
+	}
+
+	wait := time.Second
+	for i := 1; i < n && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, maxBackoff)
+}
+
+// retryAfter reads a Retry-After value, either seconds or an HTTP date.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+
+	at, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	return max(at.Sub(now), 0), true
+}
+
+// replay copies r for another attempt with a fresh body.
+func replay(r *http.Request) (*http.Request, error) {
+	next := r.Clone(r.Context())
+	if r.Body != nil && r.Body != http.NoBody {
+		body, err := r.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("replaying request body: %w", err)
+		}
+		next.Body = body
+	}
+	return next, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
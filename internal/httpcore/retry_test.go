@@ -0,0 +1,198 @@
+package httpcore
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRetryOn(t *testing.T) {
+	on, err := ParseRetryOn(DefaultRetryOn)
+	require.NoError(t, err)
+	assert.True(t, on.Connect)
+	assert.True(t, on.matches(503))
+	assert.True(t, on.matches(429))
+	assert.False(t, on.matches(501))
+	assert.False(t, on.matches(404))
+
+	on, err = ParseRetryOn("4xx, 5xx")
+	require.NoError(t, err)
+	assert.False(t, on.Connect)
+	assert.True(t, on.matches(404))
+	assert.True(t, on.matches(501))
+	assert.False(t, on.matches(302))
+
+	_, err = ParseRetryOn("connect,timeouts")
+	assert.ErrorContains(t, err, `invalid retry condition "timeouts"`)
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	d, ok := retryAfter("120", now)
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Minute, d)
+
+	d, ok = retryAfter("Wed, 01 Jan 2025 12:00:30 GMT", now)
+	assert.True(t, ok)
+	assert.Equal(t, 30*time.Second, d)
+
+	d, ok = retryAfter("Wed, 01 Jan 2025 11:00:00 GMT", now)
+	assert.True(t, ok)
+	assert.Zero(t, d)
+
+	_, ok = retryAfter("soon", now)
+	assert.False(t, ok)
+	_, ok = retryAfter("-1", now)
+	assert.False(t, ok)
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{}
+	assert.Equal(t, time.Second, p.backoff(1))
+	assert.Equal(t, 2*time.Second, p.backoff(2))
+	assert.Equal(t, 8*time.Second, p.backoff(4))
+	assert.Equal(t, maxBackoff, p.backoff(100))
+
+	p.Delay = 300 * time.Millisecond
+	assert.Equal(t, p.Delay, p.backoff(5))
+}
+
+func TestClient_Retry(t *testing.T) {
+	var calls atomic.Int32
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer srv.Close()
+
+	req, err := NewRequest(srv.URL)
+	require.NoError(t, err)
+	req.SetMethod(http.MethodPost)
+	req.SetBody([]byte(`{"id":1}`), "application/json")
+
+	on, err := ParseRetryOn(DefaultRetryOn)
+	require.NoError(t, err)
+
+	var notified []Attempt
+	client := NewClient(
+		WithRetry(RetryPolicy{Retries: 3, Delay: time.Millisecond, On: on}),
+		WithRetryNotify(func(a Attempt) { notified = append(notified, a) }),
+	)
+
+	resp, err := client.Send(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", string(resp.Body()))
+	assert.Equal(t, []string{`{"id":1}`, `{"id":1}`, `{"id":1}`}, bodies)
+
+	require.Len(t, resp.Attempts(), 2)
+	assert.Equal(t, resp.Attempts(), notified)
+	assert.Equal(t, http.StatusBadGateway, resp.Attempts()[0].StatusCode)
+	assert.Equal(t, time.Millisecond, resp.Attempts()[0].Wait)
+	assert.True(t, resp.Attempts()[1].RetryAfter)
+	assert.Zero(t, resp.Attempts()[1].Wait)
+
+	str, err := resp.ToString()
+	require.NoError(t, err)
+	assert.Contains(t, str, "Retries:")
+	assert.Contains(t, str, "waited 0s (Retry-After)")
+}
+
+func TestClient_Retry_Stops(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	on, err := ParseRetryOn(DefaultRetryOn)
+	require.NoError(t, err)
+	client := NewClient(WithRetry(RetryPolicy{Retries: 3, Delay: time.Millisecond, MaxTime: time.Second, On: on}))
+
+	// statuses that aren't retried come back right away
+	req, err := NewRequest(srv.URL + "/missing")
+	require.NoError(t, err)
+	resp, err := client.Send(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.ToHTTP().StatusCode)
+	assert.Empty(t, resp.Attempts())
+
+	// a Retry-After beyond the retry time limit isn't waited for
+	req, err = NewRequest(srv.URL)
+	require.NoError(t, err)
+	resp, err = client.Send(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.ToHTTP().StatusCode)
+	assert.Empty(t, resp.Attempts())
+}
+
+func TestClient_Retry_ConnectionErrors(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+
+	on, err := ParseRetryOn("connect")
+	require.NoError(t, err)
+	client := NewClient(WithRetry(RetryPolicy{Retries: 2, Delay: time.Millisecond, On: on}))
+
+	req, err := NewRequest("http://" + addr)
+	require.NoError(t, err)
+
+	// printing the request must not leave a body behind that can't be replayed
+	_, err = req.ToString()
+	require.NoError(t, err)
+
+	_, err = client.Send(context.Background(), req)
+	assert.ErrorContains(t, err, "giving up after 3 attempts")
+	assert.ErrorContains(t, err, "connection refused")
+}
+
+func TestClient_Retry_CertErrors(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer srv.Close()
+
+	on, err := ParseRetryOn("connect")
+	require.NoError(t, err)
+	var attempts int
+	client := NewClient(
+		WithRetry(RetryPolicy{Retries: 3, Delay: time.Millisecond, On: on}),
+		WithRetryNotify(func(Attempt) { attempts++ }),
+	)
+
+	req, err := NewRequest(srv.URL)
+	require.NoError(t, err)
+
+	_, err = client.Send(context.Background(), req)
+	assert.ErrorContains(t, err, "certificate")
+	assert.NotContains(t, err.Error(), "giving up")
+	assert.Zero(t, attempts)
+	assert.Zero(t, hits.Load())
+}
@@ -0,0 +1,324 @@
+package httpcore
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Settings configure how a request is sent rather than what is sent. they
+// can be stored in request files and are overridden by command line flags.
+// nil fields keep the client defaults.
+type Settings struct {
+	ConnectTimeout *Duration `json:"connect_timeout,omitempty"`
+	TLSTimeout     *Duration `json:"tls_timeout,omitempty"`
+	HeaderTimeout  *Duration `json:"header_timeout,omitempty"`
+	MaxTime        *Duration `json:"max_time,omitempty"`
+
+	Follow          *bool `json:"follow,omitempty"`
+	MaxRedirects    *int  `json:"max_redirects,omitempty"`
+	LocationTrusted *bool `json:"location_trusted,omitempty"`
+
+	// Cert is a PEM certificate, with the key unless Key is set, or a
+	// PKCS#12 bundle unlocked by CertPassword
+	Cert         string `json:"cert,omitempty"`
+	Key          string `json:"key,omitempty"`
+	CertPassword string `json:"cert_password,omitempty"`
+	CACert       string `json:"cacert,omitempty"`
+	CAPath       string `json:"capath,omitempty"`
+	Insecure     *bool  `json:"insecure,omitempty"`
+	TLSMin       string `json:"tls_min,omitempty"`
+	TLSMax       string `json:"tls_max,omitempty"`
+	ServerName   string `json:"server_name,omitempty"`
+
+	// Proxy is an http, https or socks5 proxy URL, credentials included.
+	// NoProxy lists hosts reached directly, like the NO_PROXY variable
+	Proxy   string `json:"proxy,omitempty"`
+	NoProxy string `json:"no_proxy,omitempty"`
+
+	// HTTPVersion is HTTP11, HTTP2 or HTTP2PriorKnowledge
+	HTTPVersion string `json:"http_version,omitempty"`
+	Compressed  *bool  `json:"compressed,omitempty"`
+
+	// Retry is how many times a failed request is repeated, RetryOn which
+	// failures count, DefaultRetryOn if empty
+	Retry        *int      `json:"retry,omitempty"`
+	RetryDelay   *Duration `json:"retry_delay,omitempty"`
+	RetryMaxTime *Duration `json:"retry_max_time,omitempty"`
+	RetryOn      string    `json:"retry_on,omitempty"`
+
+	// Resolve and ConnectTo take curl's "host:port:addr" and
+	// "host:port:tohost:toport" rules. DNSServers is a comma separated
+	// list and IPVersion "4" or "6"
+	Resolve    []string `json:"resolve,omitempty"`
+	ConnectTo  []string `json:"connect_to,omitempty"`
+	DNSServers string   `json:"dns_servers,omitempty"`
+	IPVersion  string   `json:"ip_version,omitempty"`
+
+	// UnixSocket is a socket path every request is sent through, instead
+	// of connecting to the URL's host
+	UnixSocket string `json:"unix_socket,omitempty"`
+}
+
+// Merge returns s with every field set in o replacing its own.
+func (s Settings) Merge(o Settings) Settings {
+	if o.ConnectTimeout != nil {
+		s.ConnectTimeout = o.ConnectTimeout
+	}
+	if o.TLSTimeout != nil {
+		s.TLSTimeout = o.TLSTimeout
+	}
+	if o.HeaderTimeout != nil {
+		s.HeaderTimeout = o.HeaderTimeout
+	}
+	if o.MaxTime != nil {
+		s.MaxTime = o.MaxTime
+	}
+	if o.Follow != nil {
+		s.Follow = o.Follow
+	}
+	if o.MaxRedirects != nil {
+		s.MaxRedirects = o.MaxRedirects
+	}
+	if o.LocationTrusted != nil {
+		s.LocationTrusted = o.LocationTrusted
+	}
+
+	for _, f := range []struct{ dst, src *string }{
+		{&s.Cert, &o.Cert},
+		{&s.Key, &o.Key},
+		{&s.CertPassword, &o.CertPassword},
+		{&s.CACert, &o.CACert},
+		{&s.CAPath, &o.CAPath},
+		{&s.TLSMin, &o.TLSMin},
+		{&s.TLSMax, &o.TLSMax},
+		{&s.ServerName, &o.ServerName},
+		{&s.Proxy, &o.Proxy},
+		{&s.NoProxy, &o.NoProxy},
+		{&s.HTTPVersion, &o.HTTPVersion},
+		{&s.RetryOn, &o.RetryOn},
+		{&s.DNSServers, &o.DNSServers},
+		{&s.IPVersion, &o.IPVersion},
+		{&s.UnixSocket, &o.UnixSocket},
+	} {
+		if *f.src != "" {
+			*f.dst = *f.src
+		}
+	}
+	if o.Insecure != nil {
+		s.Insecure = o.Insecure
+	}
+	if o.Compressed != nil {
+		s.Compressed = o.Compressed
+	}
+	if o.Retry != nil {
+		s.Retry = o.Retry
+	}
+	if o.Resolve != nil {
+		s.Resolve = o.Resolve
+	}
+	if o.ConnectTo != nil {
+		s.ConnectTo = o.ConnectTo
+	}
+	if o.RetryDelay != nil {
+		s.RetryDelay = o.RetryDelay
+	}
+	if o.RetryMaxTime != nil {
+		s.RetryMaxTime = o.RetryMaxTime
+	}
+
+	return s
+}
+
+// ClientOptions turns the settings into options for NewClient. it fails if
+// certificates can't be loaded or a value like a TLS version, the proxy or
+// the retry conditions is invalid.
+func (s Settings) ClientOptions() ([]ClientOption, error) {
+	var opts []ClientOption
+
+	if s.ConnectTimeout != nil {
+		opts = append(opts, WithConnectTimeout(s.ConnectTimeout.Duration))
+	}
+	if s.TLSTimeout != nil {
+		opts = append(opts, WithTLSHandshakeTimeout(s.TLSTimeout.Duration))
+	}
+	if s.HeaderTimeout != nil {
+		opts = append(opts, WithResponseHeaderTimeout(s.HeaderTimeout.Duration))
+	}
+	if s.MaxTime != nil {
+		opts = append(opts, WithTimeout(s.MaxTime.Duration))
+	}
+	if s.Follow != nil && *s.Follow {
+		max := DefaultMaxRedirects
+		if s.MaxRedirects != nil {
+			max = *s.MaxRedirects
+		}
+		opts = append(opts, WithRedirects(max))
+	}
+	if s.LocationTrusted != nil {
+		opts = append(opts, WithLocationTrusted(*s.LocationTrusted))
+	}
+	if s.Compressed != nil {
+		opts = append(opts, WithCompression(*s.Compressed))
+	}
+
+	if s.Retry != nil && *s.Retry > 0 {
+		p, err := s.retryPolicy()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithRetry(p))
+	}
+
+	dialOpts, err := s.dialOptions()
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, dialOpts...)
+
+	if s.HTTPVersion != "" {
+		p, err := protocols(s.HTTPVersion)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithProtocols(p))
+	}
+
+	cfg, err := s.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	if cfg != nil {
+		opts = append(opts, WithTLSConfig(cfg))
+	}
+
+	proxy, err := s.ProxyFunc()
+	if err != nil {
+		return nil, err
+	}
+	if proxy != nil {
+		opts = append(opts, WithProxy(proxy))
+	}
+
+	// after the proxy, since a socket replaces it
+	if s.UnixSocket != "" {
+		opts = append(opts, WithUnixSocket(s.UnixSocket))
+	}
+
+	return opts, nil
+}
+
+func (s Settings) dialOptions() ([]ClientOption, error) {
+	var opts []ClientOption
+
+	for _, r := range s.Resolve {
+		rule, err := ParseResolve(r)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithResolve(rule))
+	}
+	for _, r := range s.ConnectTo {
+		rule, err := ParseConnectTo(r)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithConnectTo(rule))
+	}
+
+	if s.DNSServers != "" {
+		var servers []string
+		for _, server := range strings.Split(s.DNSServers, ",") {
+			if server = strings.TrimSpace(server); server != "" {
+				servers = append(servers, server)
+			}
+		}
+		if len(servers) != 0 {
+			opts = append(opts, WithDNSServers(servers...))
+		}
+	}
+
+	v, err := ParseIPVersion(s.IPVersion)
+	if err != nil {
+		return nil, err
+	}
+	if v != 0 {
+		opts = append(opts, WithIPVersion(v))
+	}
+
+	return opts, nil
+}
+
+func (s Settings) retryPolicy() (RetryPolicy, error) {
+	p := RetryPolicy{Retries: *s.Retry}
+	if s.RetryDelay != nil {
+		p.Delay = s.RetryDelay.Duration
+	}
+	if s.RetryMaxTime != nil {
+		p.MaxTime = s.RetryMaxTime.Duration
+	}
+
+	on := s.RetryOn
+	if on == "" {
+		on = DefaultRetryOn
+	}
+
+	var err error
+	p.On, err = ParseRetryOn(on)
+	return p, err
+}
+
+// Duration is written as a Go duration string like "1m30s". plain numbers
+// are read as seconds, the way curl takes them.
+type Duration struct {
+	time.Duration
+}
+
+// ParseDuration accepts "1.5", meaning seconds, or any time.ParseDuration
+// string. zero disables a timeout, negative values are rejected.
+func ParseDuration(s string) (Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		secs, numErr := strconv.ParseFloat(s, 64)
+		if numErr != nil {
+			return Duration{}, fmt.Errorf("invalid duration %q: use seconds or a unit like 500ms, 10s, 1m", s)
+		}
+		d = time.Duration(secs * float64(time.Second))
+	}
+
+	if d < 0 {
+		return Duration{}, fmt.Errorf("invalid duration %q: must not be negative", s)
+	}
+
+	return Duration{d}, nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var secs float64
+	if err := json.Unmarshal(data, &secs); err == nil {
+		parsed, err := ParseDuration(strconv.FormatFloat(secs, 'f', -1, 64))
+		if err != nil {
+			return err
+		}
+		*d = parsed
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string or a number of seconds")
+	}
+
+	parsed, err := ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
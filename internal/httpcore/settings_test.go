@@ -0,0 +1,60 @@
+package httpcore
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDuration(t *testing.T) {
+	testcases := []struct {
+		Input     string
+		Expected  time.Duration
+		ExpectErr bool
+	}{
+		{Input: "10", Expected: 10 * time.Second},
+		{Input: "0.5", Expected: 500 * time.Millisecond},
+		{Input: "1m30s", Expected: 90 * time.Second},
+		{Input: "0", Expected: 0},
+		{Input: "-1s", ExpectErr: true},
+		{Input: "soon", ExpectErr: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Input, func(t *testing.T) {
+			d, err := ParseDuration(tc.Input)
+			if tc.ExpectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.Expected, d.Duration)
+		})
+	}
+}
+
+func TestSettings_JSON(t *testing.T) {
+	var s Settings
+	err := json.Unmarshal([]byte(`{"connect_timeout": 2, "max_time": "1m"}`), &s)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2*time.Second, s.ConnectTimeout.Duration)
+	assert.Equal(t, time.Minute, s.MaxTime.Duration)
+	assert.Nil(t, s.TLSTimeout)
+
+	data, err := json.Marshal(s)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"connect_timeout": "2s", "max_time": "1m0s"}`, string(data))
+
+	header := Duration{time.Second}
+	merged := s.Merge(Settings{MaxTime: &header, HeaderTimeout: &header})
+	assert.Equal(t, 2*time.Second, merged.ConnectTimeout.Duration)
+	assert.Equal(t, time.Second, merged.MaxTime.Duration)
+	assert.Equal(t, time.Second, merged.HeaderTimeout.Duration)
+	opts, err := merged.ClientOptions()
+	require.NoError(t, err)
+	assert.Len(t, opts, 3)
+}
@@ -0,0 +1,206 @@
+package httpcore
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss/tree"
+)
+
+// Timing breaks the last request of an exchange down into its phases.
+// phases that didn't happen, like DNS on a reused connection, are zero.
+type Timing struct {
+	DNSLookup    time.Duration
+	TCPConnect   time.Duration
+	TLSHandshake time.Duration
+	// Send is writing the request once a connection is ready
+	Send time.Duration
+	// Wait is the time from the request being written until the first
+	// response byte, i.e. the server think time
+	Wait time.Duration
+	// TTFB is the time from the start until the first response byte
+	TTFB     time.Duration
+	Transfer time.Duration
+	Total    time.Duration
+
+	Reused     bool
+	RemoteAddr string
+}
+
+type timingJSON struct {
+	DNSLookup    float64 `json:"dns_ms"`
+	TCPConnect   float64 `json:"connect_ms"`
+	TLSHandshake float64 `json:"tls_ms"`
+	Send         float64 `json:"send_ms"`
+	Wait         float64 `json:"wait_ms"`
+	TTFB         float64 `json:"ttfb_ms"`
+	Transfer     float64 `json:"transfer_ms"`
+	Total        float64 `json:"total_ms"`
+	Reused       bool    `json:"reused"`
+	RemoteAddr   string  `json:"remote_addr,omitempty"`
+}
+
+// MarshalJSON writes durations as fractional milliseconds.
+func (t Timing) MarshalJSON() ([]byte, error) {
+	return json.Marshal(timingJSON{
+		DNSLookup:    ms(t.DNSLookup),
+		TCPConnect:   ms(t.TCPConnect),
+		TLSHandshake: ms(t.TLSHandshake),
+		Send:         ms(t.Send),
+		Wait:         ms(t.Wait),
+		TTFB:         ms(t.TTFB),
+		Transfer:     ms(t.Transfer),
+		Total:        ms(t.Total),
+		Reused:       t.Reused,
+		RemoteAddr:   t.RemoteAddr,
+	})
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// waterfallWidth is the width of the bars in the rendered waterfall.
+const waterfallWidth = 40
+
+// Tree renders the phases as a waterfall, each bar starting where the
+// previous phase ended.
+func (t Timing) Tree() *tree.Tree {
+	phases := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"DNS lookup", t.DNSLookup},
+		{"TCP connect", t.TCPConnect},
+		{"TLS handshake", t.TLSHandshake},
+		{"Request sent", t.Send},
+		{"Waiting (TTFB)", t.Wait},
+		{"Content transfer", t.Transfer},
+	}
+
+	root := tree.Root(fmt.Sprintf("Timing: %s total, %s to first byte", round(t.Total), round(t.TTFB)))
+
+	var offset time.Duration
+	for _, p := range phases {
+		start, width := 0, 0
+		if t.Total > 0 {
+			start = int(int64(offset) * waterfallWidth / int64(t.Total))
+			width = int(int64(p.d) * waterfallWidth / int64(t.Total))
+		}
+		if p.d > 0 && width == 0 {
+			width = 1
+		}
+		start = min(start, waterfallWidth-width)
+
+		bar := strings.Repeat(" ", start) + strings.Repeat("█", width) + strings.Repeat(" ", waterfallWidth-start-width)
+		root.Child(fmt.Sprintf("%-16s %9s │%s│", p.name, round(p.d), bar))
+
+		offset += p.d
+	}
+
+	conn := "new connection"
+	if t.Reused {
+		conn = "reused connection"
+	}
+	if t.RemoteAddr != "" {
+		conn += " to " + t.RemoteAddr
+	}
+	root.Child(conn)
+
+	return root
+}
+
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	default:
+		return d.Round(time.Microsecond)
+	}
+}
+
+// tracer collects httptrace events. callbacks may come from other
+// goroutines, e.g. parallel dials, hence the mutex.
+type tracer struct {
+	mu sync.Mutex
+
+	start, dnsStart, dnsDone, connectStart, connectDone time.Time
+	tlsStart, tlsDone, gotConn, wroteRequest, firstByte time.Time
+
+	reused bool
+	addr   string
+}
+
+func newTracer(ctx context.Context) (*tracer, context.Context) {
+	t := &tracer{start: time.Now()}
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.mark(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.mark(&t.dnsDone) },
+		ConnectStart: func(network, addr string) {
+			t.mark(&t.connectStart)
+		},
+		ConnectDone: func(network, addr string, err error) {
+			if err == nil {
+				t.mark(&t.connectDone)
+			}
+		},
+		TLSHandshakeStart: func() { t.mark(&t.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { t.mark(&t.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.gotConn = time.Now()
+			t.reused = info.Reused
+			if info.Conn != nil {
+				t.addr = info.Conn.RemoteAddr().String()
+			}
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.mark(&t.wroteRequest) },
+		GotFirstResponseByte: func() { t.mark(&t.firstByte) },
+	}
+
+	return t, httptrace.WithClientTrace(ctx, trace)
+}
+
+// mark records the first occurrence of an event.
+func (t *tracer) mark(at *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if at.IsZero() {
+		*at = time.Now()
+	}
+}
+
+func (t *tracer) timing(done time.Time) *Timing {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return &Timing{
+		DNSLookup:    span(t.dnsStart, t.dnsDone),
+		TCPConnect:   span(t.connectStart, t.connectDone),
+		TLSHandshake: span(t.tlsStart, t.tlsDone),
+		Send:         span(t.gotConn, t.wroteRequest),
+		Wait:         span(t.wroteRequest, t.firstByte),
+		TTFB:         span(t.start, t.firstByte),
+		Transfer:     span(t.firstByte, done),
+		Total:        span(t.start, done),
+		Reused:       t.reused,
+		RemoteAddr:   t.addr,
+	}
+}
+
+func span(from, to time.Time) time.Duration {
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return 0
+	}
+	return to.Sub(from)
+}
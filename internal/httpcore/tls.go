@@ -0,0 +1,193 @@
+package httpcore
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseTLSVersion accepts "1.2", "tls1.2" or "TLSv1.2".
+func ParseTLSVersion(s string) (uint16, error) {
+	v := strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(s), "tls"), "v")
+	version, ok := tlsVersions[v]
+	if !ok {
+		return 0, fmt.Errorf("unknown TLS version %q, use 1.0, 1.1, 1.2 or 1.3", s)
+	}
+	return version, nil
+}
+
+func (s Settings) hasTLS() bool {
+	return s.Cert != "" || s.Key != "" || s.CACert != "" || s.CAPath != "" || s.Insecure != nil ||
+		s.TLSMin != "" || s.TLSMax != "" || s.ServerName != ""
+}
+
+// TLSConfig builds the client TLS configuration from the settings. without
+// any TLS setting it returns nil, leaving the client default in place.
+func (s Settings) TLSConfig() (*tls.Config, error) {
+	if !s.hasTLS() {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		MaxVersion: tls.VersionTLS13,
+		ServerName: s.ServerName,
+	}
+
+	if s.Insecure != nil {
+		cfg.InsecureSkipVerify = *s.Insecure
+	}
+
+	var err error
+	if s.TLSMin != "" {
+		if cfg.MinVersion, err = ParseTLSVersion(s.TLSMin); err != nil {
+			return nil, fmt.Errorf("minimum TLS version: %w", err)
+		}
+	}
+	if s.TLSMax != "" {
+		if cfg.MaxVersion, err = ParseTLSVersion(s.TLSMax); err != nil {
+			return nil, fmt.Errorf("maximum TLS version: %w", err)
+		}
+	}
+	if cfg.MinVersion > cfg.MaxVersion {
+		return nil, fmt.Errorf("minimum TLS version %s is above the maximum %s", s.TLSMin, s.TLSMax)
+	}
+
+	if s.CACert != "" || s.CAPath != "" {
+		cfg.RootCAs, err = loadRootCAs(s.CACert, s.CAPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if s.Cert != "" {
+		cert, err := loadClientCert(s.Cert, s.Key, s.CertPassword)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	} else if s.Key != "" {
+		return nil, fmt.Errorf("a client key needs a certificate, set one with --cert")
+	}
+
+	return cfg, nil
+}
+
+// loadRootCAs replaces the system roots with the given bundle and every
+// certificate found in dir, like curl's --cacert and --capath.
+func loadRootCAs(bundle, dir string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+
+	if bundle != "" {
+		data, err := os.ReadFile(bundle)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA bundle: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no PEM certificates found in %s", bundle)
+		}
+	}
+
+	if dir != "" {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA directory: %w", err)
+		}
+
+		found := false
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+			// unreadable files and files without certificates are skipped, the
+			// directory usually holds hash symlinks and other files too
+			data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+			if err != nil {
+				continue
+			}
+			if pool.AppendCertsFromPEM(data) {
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no PEM certificates found in %s", dir)
+		}
+	}
+
+	return pool, nil
+}
+
+// loadClientCert reads a PEM certificate with its key, which may be in the
+// same file, or a PKCS#12 bundle protected by password.
+func loadClientCert(certFile, keyFile, password string) (tls.Certificate, error) {
+	certData, err := os.ReadFile(certFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("reading client certificate: %w", err)
+	}
+
+	if !isPEM(certData) {
+		if keyFile != "" {
+			return tls.Certificate{}, fmt.Errorf("%s is not PEM, a PKCS#12 bundle already holds the key", certFile)
+		}
+		return loadPKCS12(certData, password)
+	}
+
+	keyData := certData
+	if keyFile != "" {
+		keyData, err = os.ReadFile(keyFile)
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("reading client key: %w", err)
+		}
+	}
+
+	cert, err := tls.X509KeyPair(certData, keyData)
+	if err != nil {
+		if keyFile == "" {
+			return tls.Certificate{}, fmt.Errorf("loading client certificate, pass the key with --key if it's in another file: %w", err)
+		}
+		return tls.Certificate{}, fmt.Errorf("loading client certificate: %w", err)
+	}
+
+	return cert, nil
+}
+
+func loadPKCS12(data []byte, password string) (tls.Certificate, error) {
+	key, leaf, chain, err := pkcs12.DecodeChain(data, password)
+	if errors.Is(err, pkcs12.ErrIncorrectPassword) {
+		return tls.Certificate{}, fmt.Errorf("wrong password for the PKCS#12 client certificate")
+	}
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("decoding PKCS#12 client certificate: %w", err)
+	}
+
+	cert := tls.Certificate{
+		Certificate: [][]byte{leaf.Raw},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}
+	for _, ca := range chain {
+		cert.Certificate = append(cert.Certificate, ca.Raw)
+	}
+
+	return cert, nil
+}
+
+func isPEM(data []byte) bool {
+	block, _ := pem.Decode(bytes.TrimSpace(data))
+	return block != nil
+}
@@ -0,0 +1,185 @@
+package httpcore
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, name string, parent *testCert, tmpl x509.Certificate) testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl.SerialNumber = big.NewInt(time.Now().UnixNano())
+	tmpl.Subject = pkix.Name{CommonName: name}
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+
+	signer, signerKey := &tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return testCert{cert: cert, key: key}
+}
+
+func (c testCert) writePEM(t *testing.T, dir, name string, withKey bool) string {
+	t.Helper()
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})
+	if withKey {
+		der, err := x509.MarshalPKCS8PrivateKey(c.key)
+		require.NoError(t, err)
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})...)
+	}
+
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func (c testCert) tlsCert() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key, Leaf: c.cert}
+}
+
+func TestSettings_TLS(t *testing.T) {
+	dir := t.TempDir()
+
+	ca := newTestCert(t, "test CA", nil, x509.Certificate{
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	})
+	server := newTestCert(t, "api.internal", &ca, x509.Certificate{
+		DNSNames:    []string{"api.internal"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	client := newTestCert(t, "ghostman", &ca, x509.Certificate{
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+
+	caFile := ca.writePEM(t, dir, "ca.pem", false)
+	clientFile := client.writePEM(t, dir, "client.pem", true)
+	caDir := filepath.Join(dir, "certs")
+	require.NoError(t, os.Mkdir(caDir, 0o700))
+	ca.writePEM(t, caDir, "ca.crt", false)
+
+	p12, err := pkcs12.Modern.Encode(client.key, client.cert, []*x509.Certificate{ca.cert}, "s3cret")
+	require.NoError(t, err)
+	p12File := filepath.Join(dir, "client.p12")
+	require.NoError(t, os.WriteFile(p12File, p12, 0o600))
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{server.tlsCert()},
+		ClientCAs:    roots,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	url := "https://127.0.0.1:" + port
+
+	yes := true
+	testcases := []struct {
+		Name     string
+		Settings Settings
+		Err      string
+	}{
+		{
+			Name:     "PEM cert with key in the same file",
+			Settings: Settings{CACert: caFile, Cert: clientFile, ServerName: "api.internal"},
+		},
+		{
+			Name:     "PEM cert with separate key and a CA directory",
+			Settings: Settings{CAPath: caDir, Cert: clientFile, Key: clientFile, ServerName: "api.internal"},
+		},
+		{
+			Name:     "PKCS#12 bundle",
+			Settings: Settings{CACert: caFile, Cert: p12File, CertPassword: "s3cret", ServerName: "api.internal"},
+		},
+		{
+			Name:     "insecure skips verification",
+			Settings: Settings{Insecure: &yes, Cert: clientFile},
+		},
+		{
+			Name:     "unknown CA",
+			Settings: Settings{Cert: clientFile, ServerName: "api.internal"},
+			Err:      "certificate signed by unknown authority",
+		},
+		{
+			Name:     "name mismatch without servername",
+			Settings: Settings{CACert: caFile, Cert: clientFile},
+			Err:      "127.0.0.1",
+		},
+		{
+			Name:     "wrong PKCS#12 password",
+			Settings: Settings{Cert: p12File, CertPassword: "nope"},
+			Err:      "wrong password",
+		},
+		{
+			Name:     "inverted version range",
+			Settings: Settings{TLSMin: "1.3", TLSMax: "tls1.2"},
+			Err:      "above the maximum",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			opts, err := tc.Settings.ClientOptions()
+			if err == nil {
+				var req *RequestConf
+				req, err = NewRequest(url)
+				require.NoError(t, err)
+
+				var resp *Response
+				resp, err = NewClient(opts...).Send(context.Background(), req)
+				if err == nil {
+					assert.Equal(t, "ghostman", string(resp.Body()))
+				}
+			}
+
+			if tc.Err != "" {
+				assert.ErrorContains(t, err, tc.Err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
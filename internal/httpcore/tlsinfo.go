@@ -0,0 +1,176 @@
+package httpcore
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/tree"
+	"golang.org/x/crypto/ocsp"
+)
+
+// DefaultCertWarnDays is how close to expiry a certificate gets a warning.
+const DefaultCertWarnDays = 30
+
+var (
+	warnStyle = lipgloss.NewStyle().Foreground(lipgloss.CompleteColor{
+		TrueColor: "#f59e0b",
+		ANSI256:   "214",
+		ANSI:      "3",
+	})
+	expiredStyle = lipgloss.NewStyle().Foreground(lipgloss.CompleteColor{
+		TrueColor: "#ef4444",
+		ANSI256:   "196",
+		ANSI:      "1",
+	})
+)
+
+// TLSTree describes the negotiated connection and the certificate chain the
+// server sent. certificates expiring within warnDays are highlighted.
+func TLSTree(state *tls.ConnectionState, warnDays int, now time.Time) *tree.Tree {
+	if state == nil {
+		return tree.Root("TLS: none, plain HTTP")
+	}
+
+	t := tree.Root("TLS:")
+	t.Child("Version: " + tls.VersionName(state.Version))
+	t.Child("Cipher: " + tls.CipherSuiteName(state.CipherSuite))
+
+	alpn := state.NegotiatedProtocol
+	if alpn == "" {
+		alpn = "none"
+	}
+	t.Child("ALPN: " + alpn)
+
+	if state.ServerName != "" {
+		t.Child("Server name: " + state.ServerName)
+	}
+	if state.DidResume {
+		t.Child("Session: resumed")
+	}
+
+	t.Child("OCSP stapling: " + ocspStatus(state))
+
+	if len(state.PeerCertificates) != 0 {
+		chain := tree.Root("Certificates:")
+		for i, cert := range state.PeerCertificates {
+			chain.Child(certTree(i, cert, warnDays, now))
+		}
+		t.Child(chain)
+	}
+
+	return t
+}
+
+// CertWarnings lists certificates of the chain that expired or expire within
+// warnDays.
+func CertWarnings(state *tls.ConnectionState, warnDays int, now time.Time) []string {
+	if state == nil {
+		return nil
+	}
+
+	var warnings []string
+	for _, cert := range state.PeerCertificates {
+		days := daysLeft(cert, now)
+		switch {
+		case days < 0:
+			warnings = append(warnings, fmt.Sprintf("certificate %q expired on %s",
+				certName(cert), cert.NotAfter.Format(time.DateOnly)))
+		case days <= warnDays:
+			warnings = append(warnings, fmt.Sprintf("certificate %q expires in %d days, on %s",
+				certName(cert), days, cert.NotAfter.Format(time.DateOnly)))
+		}
+	}
+	return warnings
+}
+
+func certTree(i int, cert *x509.Certificate, warnDays int, now time.Time) *tree.Tree {
+	t := tree.Root(fmt.Sprintf("#%d %s", i, certName(cert)))
+
+	t.Child("Subject: " + cert.Subject.String())
+	if sans := subjectAltNames(cert); len(sans) != 0 {
+		t.Child("SANs: " + strings.Join(sans, ", "))
+	}
+	t.Child("Issuer: " + cert.Issuer.String())
+	t.Child(fmt.Sprintf("Valid: %s to %s",
+		cert.NotBefore.UTC().Format(time.DateTime), cert.NotAfter.UTC().Format(time.DateTime)))
+
+	days := daysLeft(cert, now)
+	expiry := fmt.Sprintf("Expires in: %d days", days)
+	switch {
+	case days < 0:
+		expiry = expiredStyle.Render(fmt.Sprintf("Expired: %d days ago", -days))
+	case days <= warnDays:
+		expiry = warnStyle.Render(expiry)
+	}
+	t.Child(expiry)
+
+	t.Child("SHA-256: " + fingerprint(cert))
+
+	return t
+}
+
+func certName(cert *x509.Certificate) string {
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName
+	}
+	if len(cert.DNSNames) != 0 {
+		return cert.DNSNames[0]
+	}
+	return cert.Subject.String()
+}
+
+func subjectAltNames(cert *x509.Certificate) []string {
+	sans := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		sans = append(sans, u.String())
+	}
+	return sans
+}
+
+func daysLeft(cert *x509.Certificate, now time.Time) int {
+	return int(math.Floor(cert.NotAfter.Sub(now).Hours() / 24))
+}
+
+func fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+func ocspStatus(state *tls.ConnectionState) string {
+	if len(state.OCSPResponse) == 0 {
+		return "no"
+	}
+
+	var issuer *x509.Certificate
+	if len(state.PeerCertificates) > 1 {
+		issuer = state.PeerCertificates[1]
+	}
+
+	resp, err := ocsp.ParseResponse(state.OCSPResponse, issuer)
+	if err != nil {
+		return fmt.Sprintf("yes, unreadable: %v", err)
+	}
+
+	switch resp.Status {
+	case ocsp.Good:
+		return fmt.Sprintf("yes, good until %s", resp.NextUpdate.UTC().Format(time.DateTime))
+	case ocsp.Revoked:
+		return expiredStyle.Render(fmt.Sprintf("yes, REVOKED on %s", resp.RevokedAt.UTC().Format(time.DateOnly)))
+	default:
+		return "yes, status unknown"
+	}
+}
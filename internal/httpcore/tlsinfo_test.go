@@ -0,0 +1,103 @@
+package httpcore
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
+)
+
+func TestTLSTree(t *testing.T) {
+	ca := newTestCert(t, "test CA", nil, x509.Certificate{
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	})
+	leaf := newTestCert(t, "localhost", &ca, x509.Certificate{
+		DNSNames:    []string{"localhost"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+	})
+
+	stapled, err := ocsp.CreateResponse(ca.cert, ca.cert, ocsp.Response{
+		Status:       ocsp.Good,
+		SerialNumber: leaf.cert.SerialNumber,
+		ThisUpdate:   time.Now(),
+		NextUpdate:   time.Now().Add(24 * time.Hour),
+	}, ca.key)
+	require.NoError(t, err)
+
+	state := &tls.ConnectionState{
+		Version:            tls.VersionTLS13,
+		CipherSuite:        tls.TLS_AES_128_GCM_SHA256,
+		NegotiatedProtocol: "h2",
+		ServerName:         "localhost",
+		PeerCertificates:   []*x509.Certificate{leaf.cert, ca.cert},
+		OCSPResponse:       stapled,
+	}
+
+	out := TLSTree(state, 0, time.Now()).String()
+	assert.Contains(t, out, "Version: TLS 1.3")
+	assert.Contains(t, out, "Cipher: TLS_AES_128_GCM_SHA256")
+	assert.Contains(t, out, "ALPN: h2")
+	assert.Contains(t, out, "OCSP stapling: yes, good until")
+	assert.Contains(t, out, "#0 localhost")
+	assert.Contains(t, out, "#1 test CA")
+	assert.Contains(t, out, "SANs: localhost, 127.0.0.1")
+	assert.Contains(t, out, "Issuer: CN=test CA")
+	assert.Contains(t, out, "SHA-256: "+fingerprint(leaf.cert))
+	assert.Len(t, fingerprint(leaf.cert), 32*3-1)
+
+	assert.Contains(t, TLSTree(nil, 0, time.Now()).String(), "plain HTTP")
+}
+
+func TestCertWarnings(t *testing.T) {
+	// test certs are valid for an hour
+	cert := newTestCert(t, "example.test", nil, x509.Certificate{})
+	state := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert.cert}}
+
+	assert.Empty(t, CertWarnings(state, 30, time.Now().Add(-60*24*time.Hour)))
+
+	warnings := CertWarnings(state, 30, time.Now().Add(-10*24*time.Hour))
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], `"example.test" expires in 10 days`)
+
+	warnings = CertWarnings(state, 30, time.Now().Add(48*time.Hour))
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "expired on")
+
+	assert.Empty(t, CertWarnings(nil, 30, time.Now()))
+}
+
+func TestResponse_ShowTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	req, err := NewRequest(srv.URL)
+	require.NoError(t, err)
+
+	client := NewClient(WithTLSConfig(&tls.Config{InsecureSkipVerify: true}))
+	resp, err := client.Send(context.Background(), req)
+	require.NoError(t, err)
+	require.NotNil(t, resp.TLS())
+
+	str, err := resp.ToString()
+	require.NoError(t, err)
+	assert.NotContains(t, str, "TLS:")
+
+	resp.ShowTLS(true, DefaultCertWarnDays)
+	str, err = resp.ToString()
+	require.NoError(t, err)
+	assert.Contains(t, str, "TLS:")
+	assert.Contains(t, str, "Certificates:")
+	assert.Contains(t, str, "OCSP stapling: no")
+}
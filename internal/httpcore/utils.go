@@ -21,7 +21,12 @@ func ExtractQueryParams(raw string) (map[string][]string, error) {
 func DumpRequest(req *http.Request) (dump []byte, err error) {
 	var buf []byte
 
-	if req.Body != nil {
+	// a request without a body has to stay that way, or it can't be retried.
+	// DumpRequestOut would leave http.NoBody behind
+	original := req.Body
+	hasBody := original != nil && original != http.NoBody
+
+	if hasBody {
 		buf, err = io.ReadAll(req.Body)
 		if err != nil {
 			return nil, fmt.Errorf("reading request body: %w", err)
@@ -31,7 +36,11 @@ func DumpRequest(req *http.Request) (dump []byte, err error) {
 
 	dump, err = httputil.DumpRequestOut(req, true)
 
-	req.Body = io.NopCloser(bytes.NewReader(buf))
+	if hasBody {
+		req.Body = io.NopCloser(bytes.NewReader(buf))
+	} else {
+		req.Body = original
+	}
 
 	if err != nil {
 		return nil, fmt.Errorf("dumping request: %w", err)
@@ -79,6 +88,12 @@ func FormatBytes(bytes int64) string {
 }
 
 func DetectSchema(rawURL string) (fullURL string, err error) {
+	return NewClient().DetectSchema(rawURL)
+}
+
+// DetectSchema adds https:// or http:// to a URL without a scheme, whichever
+// the host answers a HEAD request on first.
+func (c *Client) DetectSchema(rawURL string) (fullURL string, err error) {
 	if strings.HasPrefix(rawURL, "http://") || strings.HasPrefix(rawURL, "https://") {
 		return rawURL, nil
 	}
@@ -93,8 +108,9 @@ func DetectSchema(rawURL string) (fullURL string, err error) {
 		return "", fmt.Errorf("doing HEAD request to detect HTTP/S schema: %w", err)
 	}
 
-	_, err = client.Do(req)
+	resp, err := c.client.Do(req)
 	if err == nil {
+		resp.Body.Close()
 		return rawURL, nil
 	}
 
@@ -105,8 +121,9 @@ func DetectSchema(rawURL string) (fullURL string, err error) {
 		return "", fmt.Errorf("doing HEAD request to detect HTTP/S: %w", err)
 	}
 
-	_, err = client.Do(req)
+	resp, err = c.client.Do(req)
 	if err == nil {
+		resp.Body.Close()
 		return rawURL, nil
 	}
 
@@ -54,15 +54,15 @@ func TestDetectProtocolWithMock(t *testing.T) {
 
 	for _, tc := range testcases {
 		t.Run(tc.Name, func(t *testing.T) {
-			client = &http.Client{
-				Transport: &mockTransport{
+			client := NewClient(
+				WithTransport(&mockTransport{
 					httpsSuccess: tc.HTTPSResponse,
 					httpSuccess:  tc.HTTPResponse,
-				},
-				Timeout: 5 * time.Second,
-			}
+				}),
+				WithTimeout(5*time.Second),
+			)
 
-			result, err := DetectSchema(tc.Input)
+			result, err := client.DetectSchema(tc.Input)
 
 			if tc.ExpectErr {
 				assert.Error(t, err)
@@ -0,0 +1,212 @@
+package httpcore
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Vars holds values for {{name}} placeholders in request files.
+type Vars map[string]string
+
+var varPattern = regexp.MustCompile(`{{\s*([^{}\s]+)\s*}}`)
+
+// MissingVarsError lists every placeholder that had no value.
+type MissingVarsError struct {
+	Names []string
+}
+
+func (e *MissingVarsError) Error() string {
+	return fmt.Sprintf("unresolved variables: %s", strings.Join(e.Names, ", "))
+}
+
+// VarCycleError is returned for variables that reference themselves,
+// directly or through others. Cycle starts and ends with the same name.
+type VarCycleError struct {
+	Cycle []string
+}
+
+func (e *VarCycleError) Error() string {
+	return fmt.Sprintf("variable cycle: %s", strings.Join(e.Cycle, " -> "))
+}
+
+// Merge returns a copy of v overridden by every layer in order.
+func (v Vars) Merge(layers ...Vars) Vars {
+	merged := maps.Clone(v)
+	if merged == nil {
+		merged = make(Vars)
+	}
+	for _, layer := range layers {
+		maps.Copy(merged, layer)
+	}
+	return merged
+}
+
+// expander replaces placeholders with vars, noting the unknown ones and
+// the first reference cycle it runs into.
+type expander struct {
+	vars    Vars
+	missing map[string]bool
+	cycle   []string
+}
+
+// expand replaces every known placeholder in s. unknown and cyclic ones are
+// left as they are. stack holds the names being expanded to get to s.
+func (e *expander) expand(s string, stack []string) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+
+	return varPattern.ReplaceAllStringFunc(s, func(m string) string {
+		name := varPattern.FindStringSubmatch(m)[1]
+
+		if i := slices.Index(stack, name); i >= 0 {
+			if e.cycle == nil {
+				e.cycle = append(slices.Clone(stack[i:]), name)
+			}
+			return m
+		}
+
+		val, ok := e.vars[name]
+		if !ok {
+			e.missing[name] = true
+			return m
+		}
+
+		return e.expand(val, append(stack, name))
+	})
+}
+
+// Interpolate returns a copy of the request with placeholders in every field
+// resolved from vars.
+func (r RequestSerializable) Interpolate(vars Vars) (RequestSerializable, error) {
+	e := &expander{vars: vars, missing: make(map[string]bool)}
+	expand := func(s string) string {
+		return e.expand(s, nil)
+	}
+
+	out := r
+	out.Method = expand(r.Method)
+	out.URL = expand(r.URL)
+	out.QueryParams = expandValues(r.QueryParams, expand)
+	out.Headers = expandValues(r.Headers, expand)
+
+	if r.Cookies != nil {
+		out.Cookies = make([]Cookie, len(r.Cookies))
+		for i, c := range r.Cookies {
+			c.Name = expand(c.Name)
+			c.Value = expand(c.Value)
+			c.Domain = expand(c.Domain)
+			c.Path = expand(c.Path)
+			out.Cookies[i] = c
+		}
+	}
+
+	if r.Body != nil {
+		body := r.Body.interpolate(expand)
+		out.Body = &body
+	}
+
+	if r.Auth != nil {
+		auth := *r.Auth
+		auth.Username = expand(auth.Username)
+		auth.Password = expand(auth.Password)
+		auth.Token = expand(auth.Token)
+		out.Auth = &auth
+	}
+
+	if r.Settings != nil {
+		settings := r.Settings.interpolate(expand)
+		out.Settings = &settings
+	}
+
+	var errs []error
+	if len(e.missing) != 0 {
+		errs = append(errs, &MissingVarsError{Names: slices.Sorted(maps.Keys(e.missing))})
+	}
+	if e.cycle != nil {
+		errs = append(errs, &VarCycleError{Cycle: e.cycle})
+	}
+	if len(errs) != 0 {
+		return r, errors.Join(errs...)
+	}
+
+	return out, nil
+}
+
+func (h BodySpec) interpolate(expand func(string) string) BodySpec {
+	if h.Text != nil {
+		text := expand(*h.Text)
+		h.Text = &text
+	}
+
+	if h.File != nil {
+		file := expand(*h.File)
+		h.File = &file
+	}
+
+	if h.FormData != nil {
+		form := expandValues(*h.FormData, expand)
+		h.FormData = &form
+	}
+
+	if h.MultipartFields != nil {
+		fields := make([]MultipartField, len(*h.MultipartFields))
+		for i, f := range *h.MultipartFields {
+			fields[i] = MultipartField{
+				Name: expand(f.Name),
+				Text: expand(f.Text),
+				File: expand(f.File),
+			}
+		}
+		h.MultipartFields = &fields
+	}
+
+	return h
+}
+
+// interpolate expands the string settings, so proxy credentials, certificate
+// passwords and paths can come from vars as well.
+func (s Settings) interpolate(expand func(string) string) Settings {
+	for _, f := range []*string{
+		&s.Cert, &s.Key, &s.CertPassword, &s.CACert, &s.CAPath, &s.TLSMin, &s.TLSMax, &s.ServerName,
+		&s.Proxy, &s.NoProxy, &s.HTTPVersion, &s.RetryOn, &s.DNSServers, &s.IPVersion, &s.UnixSocket,
+	} {
+		*f = expand(*f)
+	}
+
+	s.Resolve = expandSlice(s.Resolve, expand)
+	s.ConnectTo = expandSlice(s.ConnectTo, expand)
+
+	return s
+}
+
+func expandSlice(vals []string, expand func(string) string) []string {
+	if vals == nil {
+		return nil
+	}
+
+	out := make([]string, len(vals))
+	for i, v := range vals {
+		out[i] = expand(v)
+	}
+	return out
+}
+
+func expandValues(m map[string][]string, expand func(string) string) map[string][]string {
+	if m == nil {
+		return nil
+	}
+
+	out := make(map[string][]string, len(m))
+	for k, vals := range m {
+		key := expand(k)
+		for _, v := range vals {
+			out[key] = append(out[key], expand(v))
+		}
+	}
+	return out
+}
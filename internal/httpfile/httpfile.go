@@ -0,0 +1,288 @@
+// Package httpfile reads the plain-text .http format used by the JetBrains
+// HTTP client and the VS Code REST Client extension.
+package httpfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/bigelle/ghostman/internal/collection"
+	"github.com/bigelle/ghostman/internal/httpcore"
+)
+
+// Extensions lists file extensions treated as .http files.
+var Extensions = []string{".http", ".rest"}
+
+var (
+	varLinePattern  = regexp.MustCompile(`^@([A-Za-z0-9_.\-]+)\s*=\s*(.*)$`)
+	nameLinePattern = regexp.MustCompile(`^(?:#|//)\s*@name(?:\s*=\s*|\s+)(\S.*)$`)
+	headerPattern   = regexp.MustCompile(`^([!#$%&'*+\-.^_` + "`" + `|~0-9A-Za-z]+):\s*(.*)$`)
+)
+
+var methods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+	http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodConnect,
+}
+
+func IsHTTPFile(path string) bool {
+	return slices.Contains(Extensions, strings.ToLower(filepath.Ext(path)))
+}
+
+func LoadFile(path string) (*collection.Collection, []string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening .http file: %w", err)
+	}
+	defer f.Close()
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return Parse(f, name, filepath.Dir(path))
+}
+
+type state int
+
+const (
+	stateBefore state = iota // comments, variables and the request line
+	stateHeaders
+	stateBody
+	stateHandler // response handler script, skipped
+)
+
+type block struct {
+	name  string
+	line  int
+	lines []string
+}
+
+// Parse reads every request in the file into a collection. File variables
+// become collection variables, and body includes like "< ./body.json" are
+// resolved against dir.
+func Parse(r io.Reader, name, dir string) (*collection.Collection, []string, error) {
+	c := &collection.Collection{Folder: collection.Folder{Name: name}}
+	var warnings []string
+
+	blocks, err := split(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names := make(map[string]int)
+	for _, b := range blocks {
+		p := &parser{dir: dir, vars: make(httpcore.Vars), name: b.name, line: b.line}
+
+		ser, ok, err := p.parse(b.lines)
+		if err != nil {
+			return nil, nil, err
+		}
+		for k, v := range p.vars {
+			if c.Variables == nil {
+				c.Variables = make(httpcore.Vars)
+			}
+			c.Variables[k] = v
+		}
+		warnings = append(warnings, p.warnings...)
+
+		if !ok {
+			continue
+		}
+
+		reqName := p.name
+		if reqName == "" {
+			reqName = fmt.Sprintf("request-%d", len(c.Requests)+1)
+		}
+		reqName = collection.UniqueName(strings.ReplaceAll(reqName, "/", "-"), "%s-%d", names)
+
+		c.Requests = append(c.Requests, collection.Request{Name: reqName, RequestSerializable: ser})
+	}
+
+	return c, warnings, nil
+}
+
+// split cuts the file into blocks separated by ### lines.
+func split(r io.Reader) ([]block, error) {
+	var blocks []block
+	cur := block{line: 1}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	n := 0
+	for sc.Scan() {
+		n++
+		line := strings.TrimSuffix(sc.Text(), "\r")
+
+		if strings.HasPrefix(line, "###") {
+			blocks = append(blocks, cur)
+			cur = block{name: strings.TrimSpace(strings.TrimLeft(line, "#")), line: n + 1}
+			continue
+		}
+		cur.lines = append(cur.lines, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("reading .http file: %w", err)
+	}
+
+	return append(blocks, cur), nil
+}
+
+type parser struct {
+	dir      string
+	name     string
+	line     int
+	vars     httpcore.Vars
+	warnings []string
+}
+
+func (p *parser) warn(line int, format string, args ...any) {
+	p.warnings = append(p.warnings, fmt.Sprintf("line %d: %s", line, fmt.Sprintf(format, args...)))
+}
+
+func (p *parser) parse(lines []string) (ser httpcore.RequestSerializable, ok bool, err error) {
+	st := stateBefore
+	var body []string
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		lineNo := p.line + i
+
+		switch st {
+		case stateBefore:
+			switch {
+			case trimmed == "":
+			case nameLinePattern.MatchString(trimmed):
+				p.name = strings.TrimSpace(nameLinePattern.FindStringSubmatch(trimmed)[1])
+			case strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//"):
+			case varLinePattern.MatchString(trimmed):
+				m := varLinePattern.FindStringSubmatch(trimmed)
+				p.vars[m[1]] = strings.TrimSpace(m[2])
+			default:
+				ser, err = p.requestLine(trimmed, lineNo)
+				if err != nil {
+					return ser, false, err
+				}
+				ok = true
+				st = stateHeaders
+			}
+
+		case stateHeaders:
+			switch {
+			case trimmed == "":
+				st = stateBody
+			case strings.HasPrefix(trimmed, "?") || strings.HasPrefix(trimmed, "&"):
+				// query parameters continued on the next lines
+				ser.URL += trimmed
+			case strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//"):
+			default:
+				m := headerPattern.FindStringSubmatch(trimmed)
+				if m == nil {
+					return ser, false, fmt.Errorf("line %d: malformed header: %s", lineNo, trimmed)
+				}
+				if ser.Headers == nil {
+					ser.Headers = make(map[string][]string)
+				}
+				ser.Headers[m[1]] = append(ser.Headers[m[1]], strings.TrimSpace(m[2]))
+			}
+
+		case stateBody:
+			switch {
+			case strings.HasPrefix(trimmed, "> {%"):
+				p.warn(lineNo, "response handler scripts are not supported, skipped")
+				if !strings.Contains(trimmed, "%}") {
+					st = stateHandler
+				}
+			case strings.HasPrefix(trimmed, ">>") || strings.HasPrefix(trimmed, "> ") || strings.HasPrefix(trimmed, "<> "):
+				p.warn(lineNo, "%s is not supported, skipped", trimmed)
+			default:
+				body = append(body, line)
+			}
+
+		case stateHandler:
+			if strings.Contains(trimmed, "%}") {
+				st = stateBody
+			}
+		}
+	}
+
+	if !ok {
+		return ser, false, nil
+	}
+
+	ser.Body, err = p.body(body)
+	return ser, true, err
+}
+
+func (p *parser) requestLine(line string, lineNo int) (ser httpcore.RequestSerializable, err error) {
+	// the version may be "HTTP/2 (Prior Knowledge)", which has spaces
+	var version string
+	if i := strings.Index(line, " HTTP/"); i != -1 {
+		line, version = line[:i], strings.TrimSpace(line[i+1:])
+	}
+
+	fields := strings.Fields(line)
+
+	method := http.MethodGet
+	if slices.Contains(methods, strings.ToUpper(fields[0])) {
+		method = strings.ToUpper(fields[0])
+		fields = fields[1:]
+	}
+
+	if len(fields) == 0 {
+		return ser, fmt.Errorf("line %d: no URL in request line", lineNo)
+	}
+	if len(fields) > 1 {
+		return ser, fmt.Errorf("line %d: malformed request line: %s", lineNo, line)
+	}
+
+	u := fields[0]
+	if !strings.Contains(u, "://") && !strings.HasPrefix(u, "{{") {
+		u = "http://" + u
+	}
+
+	ser = httpcore.RequestSerializable{Method: method, URL: u}
+
+	switch strings.ToLower(version) {
+	case "", "http/1.0", "http/1.1":
+	case "http/2", "http/2.0":
+		ser.Settings = &httpcore.Settings{HTTPVersion: httpcore.HTTP2}
+	case "http/2 (prior knowledge)":
+		ser.Settings = &httpcore.Settings{HTTPVersion: httpcore.HTTP2PriorKnowledge}
+	default:
+		return ser, fmt.Errorf("line %d: unsupported HTTP version %s", lineNo, version)
+	}
+
+	return ser, nil
+}
+
+func (p *parser) body(lines []string) (*httpcore.BodySpec, error) {
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	first := strings.TrimSpace(lines[0])
+	if len(lines) == 1 && (strings.HasPrefix(first, "< ") || strings.HasPrefix(first, "<@ ")) {
+		path := strings.TrimSpace(strings.TrimLeft(first, "<@"))
+		if !filepath.IsAbs(path) && !strings.HasPrefix(path, "{{") {
+			path = filepath.Join(p.dir, path)
+		}
+		return &httpcore.BodySpec{Type: "content", File: &path}, nil
+	}
+
+	for _, l := range lines {
+		if strings.HasPrefix(strings.TrimSpace(l), "< ") {
+			return nil, fmt.Errorf("request at line %d: file includes mixed with other body content are not supported", p.line)
+		}
+	}
+
+	text := strings.Join(lines, "\n")
+	return &httpcore.BodySpec{Type: "content", Text: &text}, nil
+}
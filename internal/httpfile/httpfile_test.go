@@ -0,0 +1,117 @@
+package httpfile
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bigelle/ghostman/internal/httpcore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testFile = `@host = api.example.com
+@token = secret
+
+### list users
+GET https://{{host}}/users
+    ?page=1
+    &limit=20
+Accept: application/json
+
+###
+# @name create-user
+POST https://{{host}}/users HTTP/1.1
+Content-Type: application/json
+Authorization: Bearer {{token}}
+
+{
+  "name": "Rex"
+}
+
+> {%
+    client.global.set("id", response.body.id);
+%}
+
+###
+// a body from a file
+PUT https://{{host}}/avatar
+Content-Type: image/png
+
+< ./avatar.png
+
+###
+localhost:8080/health
+
+###
+# only comments here
+`
+
+func TestParse(t *testing.T) {
+	c, warnings, err := Parse(strings.NewReader(testFile), "api", "testdata")
+	require.NoError(t, err)
+
+	assert.Equal(t, "api", c.Name)
+	assert.Equal(t, httpcore.Vars{"host": "api.example.com", "token": "secret"}, c.Variables)
+	assert.Equal(t, []string{"list users", "create-user", "request-3", "request-4"}, c.Paths())
+	assert.Len(t, warnings, 1)
+
+	list := c.Requests[0]
+	assert.Equal(t, "GET", list.Method)
+	assert.Equal(t, "https://{{host}}/users?page=1&limit=20", list.URL)
+	assert.Equal(t, map[string][]string{"Accept": {"application/json"}}, list.Headers)
+	assert.Nil(t, list.Body)
+
+	create := c.Requests[1]
+	assert.Equal(t, "POST", create.Method)
+	assert.Equal(t, []string{"Bearer {{token}}"}, create.Headers["Authorization"])
+	require.NotNil(t, create.Body)
+	assert.Equal(t, "{\n  \"name\": \"Rex\"\n}", *create.Body.Text)
+
+	upload := c.Requests[2]
+	require.NotNil(t, upload.Body)
+	require.NotNil(t, upload.Body.File)
+	assert.Equal(t, filepath.Join("testdata", "avatar.png"), *upload.Body.File)
+
+	health := c.Requests[3]
+	assert.Equal(t, "GET", health.Method)
+	assert.Equal(t, "http://localhost:8080/health", health.URL)
+}
+
+func TestParse_Errors(t *testing.T) {
+	_, _, err := Parse(strings.NewReader("GET https://example.com\nnot a header\n"), "x", ".")
+	assert.ErrorContains(t, err, "line 2: malformed header")
+
+	_, _, err = Parse(strings.NewReader("GET https://example.com oops\n"), "x", ".")
+	assert.ErrorContains(t, err, "malformed request line")
+}
+
+func TestParse_DuplicateNames(t *testing.T) {
+	c, _, err := Parse(strings.NewReader("### ping\nGET http://a\n\n### ping\nGET http://b\n"), "x", ".")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ping", "ping-2"}, c.Paths())
+
+	// generated names can't take an explicit one
+	c, _, err = Parse(strings.NewReader("### ping\nGET http://a\n\n### ping\nGET http://b\n\n### ping-2\nGET http://c\n"), "x", ".")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ping", "ping-2", "ping-2-2"}, c.Paths())
+
+	c, _, err = Parse(strings.NewReader("### request-2\nGET http://a\n\n###\nGET http://b\n"), "x", ".")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"request-2", "request-2-2"}, c.Paths())
+}
+
+func TestParse_HTTPVersion(t *testing.T) {
+	c, _, err := Parse(strings.NewReader("GET https://a HTTP/2\n\n###\nGET http://b HTTP/2 (Prior Knowledge)\n\n###\nGET http://c HTTP/1.1\n"), "x", ".")
+	require.NoError(t, err)
+	require.Len(t, c.Requests, 3)
+
+	require.NotNil(t, c.Requests[0].Settings)
+	assert.Equal(t, httpcore.HTTP2, c.Requests[0].Settings.HTTPVersion)
+	require.NotNil(t, c.Requests[1].Settings)
+	assert.Equal(t, httpcore.HTTP2PriorKnowledge, c.Requests[1].Settings.HTTPVersion)
+	assert.Nil(t, c.Requests[2].Settings)
+
+	_, _, err = Parse(strings.NewReader("GET https://a HTTP/3\n"), "x", ".")
+	assert.ErrorContains(t, err, "unsupported HTTP version")
+}
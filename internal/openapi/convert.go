@@ -0,0 +1,389 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/bigelle/ghostman/internal/collection"
+	"github.com/bigelle/ghostman/internal/httpcore"
+)
+
+// BaseURLVar is the variable every generated URL starts with.
+const BaseURLVar = "baseUrl"
+
+var (
+	pathParamPattern = regexp.MustCompile(`{([^{}/]+)}`)
+	serverVarPattern = regexp.MustCompile(`{([^{}]+)}`)
+	nonWordPattern   = regexp.MustCompile(`[^A-Za-z0-9_.]+`)
+)
+
+// ToCollection creates one request per operation, grouped into folders by
+// their first tag. Path parameters become {{variables}}, required query,
+// header and cookie parameters are filled with examples or variables.
+func (s *Spec) ToCollection() (*collection.Collection, []string) {
+	var warnings []string
+	warn := func(op, format string, args ...any) {
+		warnings = append(warnings, op+": "+fmt.Sprintf(format, args...))
+	}
+
+	name := s.Info.Title
+	if name == "" {
+		name = "openapi"
+	}
+
+	c := &collection.Collection{
+		Folder:    collection.Folder{Name: name},
+		Variables: httpcore.Vars{},
+	}
+
+	if len(s.Servers) == 0 {
+		warn("spec", "no servers declared, set {{%s}} yourself", BaseURLVar)
+	} else {
+		c.Variables[BaseURLVar] = serverURL(s.Servers[0])
+	}
+
+	if auth, headers := s.security(s.Security); auth != nil || headers != nil {
+		c.Defaults = &collection.Defaults{Auth: auth, Headers: headers}
+	}
+
+	folders := make(map[string]*collection.Folder)
+	var folderOrder []string
+	names := make(map[string]int)
+
+	paths := make([]string, 0, len(s.Paths))
+	for p := range s.Paths {
+		paths = append(paths, p)
+	}
+	slices.Sort(paths)
+
+	for _, path := range paths {
+		item := s.Paths[path]
+		if item == nil {
+			continue
+		}
+
+		for _, mo := range item.Operations() {
+			op := mo.Operation
+			opName := collection.UniqueName(operationName(mo.Method, path, op), "%s-%d", names)
+
+			ser, warns := s.request(mo.Method, path, item, op)
+			for _, w := range warns {
+				warn(opName, "%s", w)
+			}
+
+			req := collection.Request{Name: opName, RequestSerializable: ser}
+
+			if len(op.Tags) == 0 {
+				c.Requests = append(c.Requests, req)
+				continue
+			}
+
+			tag := strings.ReplaceAll(op.Tags[0], "/", "-")
+			f, ok := folders[tag]
+			if !ok {
+				f = &collection.Folder{Name: tag}
+				folders[tag] = f
+				folderOrder = append(folderOrder, tag)
+			}
+			f.Requests = append(f.Requests, req)
+		}
+	}
+
+	for _, tag := range folderOrder {
+		c.Folders = append(c.Folders, *folders[tag])
+	}
+
+	return c, warnings
+}
+
+func (s *Spec) request(method, path string, item *PathItem, op *Operation) (ser httpcore.RequestSerializable, warnings []string) {
+	base := "{{" + BaseURLVar + "}}"
+	switch {
+	case len(op.Servers) != 0:
+		base = serverURL(op.Servers[0])
+	case len(item.Servers) != 0:
+		base = serverURL(item.Servers[0])
+	}
+
+	ser = httpcore.RequestSerializable{
+		Method: method,
+		URL:    strings.TrimSuffix(base, "/") + pathParamPattern.ReplaceAllString(path, "{{$1}}"),
+	}
+
+	params, err := s.parameters(item.Parameters, op.Parameters)
+	if err != nil {
+		warnings = append(warnings, err.Error())
+	}
+
+	for _, p := range params {
+		if !p.Required || p.In == "path" {
+			continue
+		}
+
+		val := s.paramValue(p)
+		switch p.In {
+		case "query":
+			if ser.QueryParams == nil {
+				ser.QueryParams = make(map[string][]string)
+			}
+			ser.QueryParams[p.Name] = append(ser.QueryParams[p.Name], val)
+		case "header":
+			if ser.Headers == nil {
+				ser.Headers = make(map[string][]string)
+			}
+			ser.Headers[p.Name] = append(ser.Headers[p.Name], val)
+		case "cookie":
+			ser.Cookies = append(ser.Cookies, httpcore.Cookie{Name: p.Name, Value: val})
+		}
+	}
+
+	if op.RequestBody != nil {
+		body, ct, err := s.body(op.RequestBody)
+		if err != nil {
+			warnings = append(warnings, err.Error())
+		}
+		if body != nil {
+			ser.Body = body
+		}
+		if body != nil && ct != "" {
+			if ser.Headers == nil {
+				ser.Headers = make(map[string][]string)
+			}
+			ser.Headers["Content-Type"] = []string{ct}
+		}
+	}
+
+	if op.Security != nil {
+		auth, headers := s.security(*op.Security)
+		if auth == nil {
+			// an empty requirement list switches inherited auth off
+			auth = &httpcore.AuthSpec{Type: "none"}
+		}
+		ser.Auth = auth
+		for k, v := range headers {
+			if ser.Headers == nil {
+				ser.Headers = make(map[string][]string)
+			}
+			ser.Headers[k] = v
+		}
+	}
+
+	return ser, warnings
+}
+
+// parameters merges path-level parameters with operation ones, the latter
+// winning on the same name and location.
+func (s *Spec) parameters(pathParams, opParams []*Parameter) ([]*Parameter, error) {
+	var out []*Parameter
+	index := make(map[string]int)
+
+	for _, list := range [][]*Parameter{pathParams, opParams} {
+		for _, p := range list {
+			resolved, err := s.parameter(p)
+			if err != nil {
+				return out, err
+			}
+			key := resolved.In + ":" + resolved.Name
+			if i, ok := index[key]; ok {
+				out[i] = resolved
+				continue
+			}
+			index[key] = len(out)
+			out = append(out, resolved)
+		}
+	}
+
+	return out, nil
+}
+
+func (s *Spec) paramValue(p *Parameter) string {
+	ex := p.Example
+	if ex == nil {
+		ex = firstExample(p.Examples)
+	}
+	if ex == nil && p.Schema != nil {
+		sc, _ := s.schema(p.Schema)
+		if sc != nil && (sc.Example != nil || sc.Default != nil || len(sc.Enum) != 0) {
+			ex = s.Example(sc)
+		}
+	}
+
+	if ex == nil {
+		return "{{" + p.Name + "}}"
+	}
+	return scalarString(ex)
+}
+
+func (s *Spec) body(b *RequestBody) (*httpcore.BodySpec, string, error) {
+	b, err := s.requestBody(b)
+	if err != nil {
+		return nil, "", err
+	}
+
+	cts := make([]string, 0, len(b.Content))
+	for ct := range b.Content {
+		cts = append(cts, ct)
+	}
+	slices.Sort(cts)
+
+	// prefer JSON, then forms, then whatever comes first
+	slices.SortStableFunc(cts, func(a, b string) int {
+		return bodyRank(a) - bodyRank(b)
+	})
+	if len(cts) == 0 {
+		return nil, "", nil
+	}
+
+	ct := cts[0]
+	mt := b.Content[ct]
+
+	ex := mt.Example
+	if ex == nil {
+		ex = firstExample(mt.Examples)
+	}
+	if ex == nil {
+		ex = s.Example(mt.Schema)
+	}
+
+	switch bodyRank(ct) {
+	case 0:
+		if ex == nil {
+			ex = map[string]any{}
+		}
+		data, err := json.MarshalIndent(ex, "", "  ")
+		if err != nil {
+			return nil, "", fmt.Errorf("encoding example body: %w", err)
+		}
+		text := string(data)
+		return &httpcore.BodySpec{Type: "content", Text: &text}, ct, nil
+
+	case 1:
+		form := make(map[string][]string)
+		if obj, ok := ex.(map[string]any); ok {
+			for k, v := range obj {
+				form[k] = []string{scalarString(v)}
+			}
+		}
+		return &httpcore.BodySpec{Type: "form", FormData: &form}, ct, nil
+
+	case 2:
+		fields := []httpcore.MultipartField{}
+		if obj, ok := ex.(map[string]any); ok {
+			keys := make([]string, 0, len(obj))
+			for k := range obj {
+				keys = append(keys, k)
+			}
+			slices.Sort(keys)
+			for _, k := range keys {
+				fields = append(fields, httpcore.MultipartField{Name: k, Text: scalarString(obj[k])})
+			}
+		}
+		// the body sets a Content-Type with the boundary it was built with
+		return &httpcore.BodySpec{Type: "multipart", MultipartFields: &fields}, "", nil
+
+	default:
+		if str, ok := ex.(string); ok {
+			return &httpcore.BodySpec{Type: "content", Text: &str}, ct, nil
+		}
+		return nil, "", fmt.Errorf("no example for %s body, skipped", ct)
+	}
+}
+
+// firstExample picks the example with the smallest name, so that output
+// doesn't depend on map order.
+func firstExample(examples map[string]Example) any {
+	if len(examples) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(examples))
+	for k := range examples {
+		keys = append(keys, k)
+	}
+	return examples[slices.Min(keys)].Value
+}
+
+func bodyRank(ct string) int {
+	switch {
+	case ct == "application/json" || strings.HasSuffix(ct, "+json"):
+		return 0
+	case ct == "application/x-www-form-urlencoded":
+		return 1
+	case ct == "multipart/form-data":
+		return 2
+	default:
+		return 3
+	}
+}
+
+// security maps the first requirement to auth or headers.
+func (s *Spec) security(reqs []map[string][]string) (*httpcore.AuthSpec, map[string][]string) {
+	for _, req := range reqs {
+		for name := range req {
+			scheme, ok := s.Components.SecuritySchemes[name]
+			if !ok || scheme == nil {
+				continue
+			}
+
+			switch {
+			case scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "bearer"):
+				return &httpcore.AuthSpec{Type: "bearer", Token: "{{token}}"}, nil
+			case scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "basic"):
+				return &httpcore.AuthSpec{Type: "basic", Username: "{{username}}", Password: "{{password}}"}, nil
+			case scheme.Type == "apiKey" && scheme.In == "header":
+				return nil, map[string][]string{scheme.Name: {"{{" + varName(scheme.Name) + "}}"}}
+			case scheme.Type == "oauth2" || scheme.Type == "openIdConnect":
+				return &httpcore.AuthSpec{Type: "bearer", Token: "{{token}}"}, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func serverURL(srv Server) string {
+	return serverVarPattern.ReplaceAllStringFunc(srv.URL, func(m string) string {
+		name := m[1 : len(m)-1]
+		if v, ok := srv.Variables[name]; ok {
+			return v.Default
+		}
+		return "{{" + name + "}}"
+	})
+}
+
+func operationName(method, path string, op *Operation) string {
+	if op.OperationID != "" {
+		return strings.ReplaceAll(op.OperationID, "/", "-")
+	}
+
+	slug := strings.Trim(nonWordPattern.ReplaceAllString(path, "-"), "-")
+	if slug == "" {
+		return strings.ToLower(method)
+	}
+	return strings.ToLower(method) + "-" + slug
+}
+
+func varName(header string) string {
+	return strings.Trim(nonWordPattern.ReplaceAllString(header, "_"), "_")
+}
+
+func scalarString(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case nil:
+		return ""
+	case []any:
+		parts := make([]string, len(val))
+		for i, p := range val {
+			parts[i] = scalarString(p)
+		}
+		return strings.Join(parts, ",")
+	case map[string]any:
+		data, _ := json.Marshal(val)
+		return string(data)
+	default:
+		return fmt.Sprint(val)
+	}
+}
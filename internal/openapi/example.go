@@ -0,0 +1,143 @@
+package openapi
+
+import "maps"
+
+// maxExampleDepth keeps recursive schemas from producing endless examples.
+const maxExampleDepth = 8
+
+// Example builds a sample value for the schema, preferring examples,
+// defaults and enums declared in the spec over generated placeholders.
+func (s *Spec) Example(sc *Schema) any {
+	return s.example(sc, 0, make(map[string]bool))
+}
+
+func (s *Spec) example(sc *Schema, depth int, visiting map[string]bool) any {
+	if sc == nil || depth > maxExampleDepth {
+		return nil
+	}
+
+	for sc.Ref != "" {
+		if visiting[sc.Ref] {
+			return nil
+		}
+		visiting[sc.Ref] = true
+		defer delete(visiting, sc.Ref)
+
+		resolved, err := s.schema(sc)
+		if err != nil || resolved == nil {
+			return nil
+		}
+		sc = resolved
+	}
+
+	switch {
+	case sc.Example != nil:
+		return sc.Example
+	case len(sc.Examples) != 0:
+		return sc.Examples[0]
+	case sc.Const != nil:
+		return sc.Const
+	case sc.Default != nil:
+		return sc.Default
+	case len(sc.Enum) != 0:
+		return sc.Enum[0]
+	}
+
+	if len(sc.AllOf) != 0 {
+		merged := make(map[string]any)
+		for _, sub := range sc.AllOf {
+			if obj, ok := s.example(sub, depth+1, visiting).(map[string]any); ok {
+				maps.Copy(merged, obj)
+			}
+		}
+		if obj, ok := s.objectExample(sc, depth, visiting).(map[string]any); ok {
+			maps.Copy(merged, obj)
+		}
+		return merged
+	}
+	if len(sc.OneOf) != 0 {
+		return s.example(sc.OneOf[0], depth+1, visiting)
+	}
+	if len(sc.AnyOf) != 0 {
+		return s.example(sc.AnyOf[0], depth+1, visiting)
+	}
+
+	switch schemaType(sc) {
+	case "object":
+		return s.objectExample(sc, depth, visiting)
+	case "array":
+		item := s.example(sc.Items, depth+1, visiting)
+		if item == nil {
+			return []any{}
+		}
+		return []any{item}
+	case "string":
+		return stringExample(sc.Format)
+	case "integer":
+		return 0
+	case "number":
+		return 0.0
+	case "boolean":
+		return false
+	default:
+		return nil
+	}
+}
+
+func (s *Spec) objectExample(sc *Schema, depth int, visiting map[string]bool) any {
+	obj := make(map[string]any, len(sc.Properties))
+	for name, prop := range sc.Properties {
+		obj[name] = s.example(prop, depth+1, visiting)
+	}
+	return obj
+}
+
+func schemaType(sc *Schema) string {
+	switch t := sc.Type.(type) {
+	case string:
+		return t
+	case []any:
+		for _, v := range t {
+			if str, ok := v.(string); ok && str != "null" {
+				return str
+			}
+		}
+	}
+
+	if sc.Properties != nil {
+		return "object"
+	}
+	if sc.Items != nil {
+		return "array"
+	}
+	return ""
+}
+
+func stringExample(format string) string {
+	switch format {
+	case "date-time":
+		return "2025-01-01T00:00:00Z"
+	case "date":
+		return "2025-01-01"
+	case "time":
+		return "00:00:00"
+	case "email":
+		return "user@example.com"
+	case "uuid":
+		return "00000000-0000-0000-0000-000000000000"
+	case "uri", "url":
+		return "https://example.com"
+	case "hostname":
+		return "example.com"
+	case "ipv4":
+		return "127.0.0.1"
+	case "ipv6":
+		return "::1"
+	case "byte":
+		return "c3RyaW5n"
+	case "password":
+		return "password"
+	default:
+		return "string"
+	}
+}
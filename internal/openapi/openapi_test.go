@@ -0,0 +1,254 @@
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bigelle/ghostman/internal/collection"
+	"github.com/bigelle/ghostman/internal/httpcore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSpec = `
+openapi: 3.0.3
+info:
+  title: Pets
+  version: "1.0"
+servers:
+  - url: https://{region}.example.com/v1
+    variables:
+      region:
+        default: eu
+security:
+  - bearerAuth: []
+components:
+  securitySchemes:
+    bearerAuth:
+      type: http
+      scheme: bearer
+  parameters:
+    Limit:
+      name: limit
+      in: query
+      required: true
+      schema:
+        type: integer
+        default: 20
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name:
+          type: string
+          example: Rex
+        born:
+          type: string
+          format: date
+        tags:
+          type: array
+          items:
+            type: string
+        owner:
+          $ref: '#/components/schemas/Owner'
+    Owner:
+      type: object
+      properties:
+        email:
+          type: string
+          format: email
+        pets:
+          type: array
+          items:
+            $ref: '#/components/schemas/Pet'
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      tags: [pets]
+      parameters:
+        - $ref: '#/components/parameters/Limit'
+        - name: X-Request-Id
+          in: header
+          required: true
+          schema:
+            type: string
+        - name: sort
+          in: query
+          schema:
+            type: string
+    post:
+      operationId: createPet
+      tags: [pets]
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+  /pets/{petId}:
+    delete:
+      tags: [pets]
+      security: []
+      parameters:
+        - name: petId
+          in: path
+          required: true
+          schema:
+            type: string
+  /health:
+    get:
+      operationId: health
+`
+
+func TestSpec_ToCollection(t *testing.T) {
+	spec, err := Load(strings.NewReader(testSpec))
+	require.NoError(t, err)
+
+	c, warnings := spec.ToCollection()
+	assert.Empty(t, warnings)
+
+	assert.Equal(t, httpcore.Vars{"baseUrl": "https://eu.example.com/v1"}, c.Variables)
+	assert.Equal(t, []string{"health", "pets/listPets", "pets/createPet", "pets/delete-pets-petId"}, c.Paths())
+
+	list, err := c.Resolve("pets/listPets")
+	require.NoError(t, err)
+	assert.Equal(t, httpcore.RequestSerializable{
+		Method:      "GET",
+		URL:         "{{baseUrl}}/pets",
+		QueryParams: map[string][]string{"limit": {"20"}},
+		Headers:     map[string][]string{"X-Request-Id": {"{{X-Request-Id}}"}},
+		Auth:        &httpcore.AuthSpec{Type: "bearer", Token: "{{token}}"},
+	}, list)
+
+	create, err := c.Resolve("pets/createPet")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"application/json"}, create.Headers["Content-Type"])
+	assert.JSONEq(t, `{
+		"name": "Rex",
+		"born": "2025-01-01",
+		"tags": ["string"],
+		"owner": {"email": "user@example.com", "pets": []}
+	}`, *create.Body.Text)
+
+	del, err := c.Resolve("pets/delete-pets-petId")
+	require.NoError(t, err)
+	assert.Equal(t, "{{baseUrl}}/pets/{{petId}}", del.URL)
+	assert.Equal(t, &httpcore.AuthSpec{Type: "none"}, del.Auth)
+}
+
+func TestSpec_Multipart(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, "name=%s", r.FormValue("name"))
+	}))
+	defer srv.Close()
+
+	spec, err := Load(strings.NewReader(`
+openapi: 3.0.3
+info: {title: Upload, version: "1.0"}
+servers: [{url: "https://example.com"}]
+paths:
+  /upload:
+    post:
+      operationId: upload
+      requestBody:
+        content:
+          multipart/form-data:
+            schema:
+              type: object
+              properties:
+                name: {type: string, example: Rex}
+`))
+	require.NoError(t, err)
+
+	c, warnings := spec.ToCollection()
+	assert.Empty(t, warnings)
+	ser, err := c.Resolve("upload")
+	require.NoError(t, err)
+	ser.URL = srv.URL + "/upload"
+
+	req, err := httpcore.NewRequestFromSerializable(ser)
+	require.NoError(t, err)
+	resp, err := httpcore.NewClient().Send(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "name=Rex", string(resp.Body()))
+}
+
+func TestSpec_CircularRef(t *testing.T) {
+	spec, err := Load(strings.NewReader(`
+openapi: 3.0.3
+info: {title: Loops, version: "1.0"}
+servers: [{url: "https://example.com"}]
+components:
+  parameters:
+    A: {$ref: '#/components/parameters/B'}
+    B: {$ref: '#/components/parameters/A'}
+  requestBodies:
+    Self: {$ref: '#/components/requestBodies/Self'}
+  schemas:
+    Self: {$ref: '#/components/schemas/Self'}
+paths:
+  /loop:
+    post:
+      operationId: loop
+      parameters:
+        - $ref: '#/components/parameters/A'
+      requestBody: {$ref: '#/components/requestBodies/Self'}
+  /schema:
+    post:
+      operationId: schema
+      requestBody:
+        content:
+          application/json:
+            schema: {$ref: '#/components/schemas/Self'}
+`))
+	require.NoError(t, err)
+
+	c, warnings := spec.ToCollection()
+	assert.Contains(t, warnings, "loop: circular $ref #/components/parameters/A")
+	assert.Contains(t, warnings, "loop: circular $ref #/components/requestBodies/Self")
+	assert.Equal(t, []string{"loop", "schema"}, c.Paths())
+
+	_, err = spec.schema(&Schema{Ref: "#/components/schemas/Self"})
+	assert.ErrorContains(t, err, "circular $ref #/components/schemas/Self")
+}
+
+func TestSpec_DuplicateNames(t *testing.T) {
+	spec, err := Load(strings.NewReader(`
+openapi: 3.0.3
+info: {title: Names, version: "1.0"}
+servers: [{url: "https://example.com"}]
+paths:
+  /a-b:
+    get: {}
+  /a/b:
+    get: {}
+  /a/b-2:
+    get: {}
+  /x:
+    get: {operationId: x}
+    put: {operationId: x}
+    post: {operationId: x-2}
+`))
+	require.NoError(t, err)
+
+	c, _ := spec.ToCollection()
+	paths := c.Paths()
+	assert.Len(t, paths, 6)
+	assert.ElementsMatch(t, slices.Compact(slices.Sorted(slices.Values(paths))), paths)
+
+	var buf bytes.Buffer
+	require.NoError(t, c.Save(&buf))
+	_, err = collection.Load(&buf)
+	require.NoError(t, err)
+}
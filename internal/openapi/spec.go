@@ -0,0 +1,209 @@
+// Package openapi generates ghostman collections from OpenAPI 3 specifications.
+package openapi
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec holds only the parts of an OpenAPI document needed to build requests.
+// JSON specs are read with the same decoder, since JSON is valid YAML.
+type Spec struct {
+	OpenAPI    string                `yaml:"openapi"`
+	Info       Info                  `yaml:"info"`
+	Servers    []Server              `yaml:"servers"`
+	Paths      map[string]*PathItem  `yaml:"paths"`
+	Components Components            `yaml:"components"`
+	Security   []map[string][]string `yaml:"security"`
+}
+
+type Info struct {
+	Title   string `yaml:"title"`
+	Version string `yaml:"version"`
+}
+
+type Server struct {
+	URL       string                    `yaml:"url"`
+	Variables map[string]ServerVariable `yaml:"variables"`
+}
+
+type ServerVariable struct {
+	Default string `yaml:"default"`
+}
+
+type PathItem struct {
+	Parameters []*Parameter `yaml:"parameters"`
+	Servers    []Server     `yaml:"servers"`
+	Get        *Operation   `yaml:"get"`
+	Put        *Operation   `yaml:"put"`
+	Post       *Operation   `yaml:"post"`
+	Delete     *Operation   `yaml:"delete"`
+	Options    *Operation   `yaml:"options"`
+	Head       *Operation   `yaml:"head"`
+	Patch      *Operation   `yaml:"patch"`
+	Trace      *Operation   `yaml:"trace"`
+}
+
+// Operations lists the operations of the path in a stable order.
+func (p PathItem) Operations() []MethodOperation {
+	all := []MethodOperation{
+		{"GET", p.Get}, {"POST", p.Post}, {"PUT", p.Put}, {"PATCH", p.Patch},
+		{"DELETE", p.Delete}, {"HEAD", p.Head}, {"OPTIONS", p.Options}, {"TRACE", p.Trace},
+	}
+
+	ops := all[:0]
+	for _, op := range all {
+		if op.Operation != nil {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+type MethodOperation struct {
+	Method    string
+	Operation *Operation
+}
+
+type Operation struct {
+	OperationID string                 `yaml:"operationId"`
+	Summary     string                 `yaml:"summary"`
+	Tags        []string               `yaml:"tags"`
+	Parameters  []*Parameter           `yaml:"parameters"`
+	RequestBody *RequestBody           `yaml:"requestBody"`
+	Servers     []Server               `yaml:"servers"`
+	Security    *[]map[string][]string `yaml:"security"`
+	Deprecated  bool                   `yaml:"deprecated"`
+}
+
+type Parameter struct {
+	Ref      string             `yaml:"$ref"`
+	Name     string             `yaml:"name"`
+	In       string             `yaml:"in"`
+	Required bool               `yaml:"required"`
+	Schema   *Schema            `yaml:"schema"`
+	Example  any                `yaml:"example"`
+	Examples map[string]Example `yaml:"examples"`
+}
+
+type RequestBody struct {
+	Ref      string               `yaml:"$ref"`
+	Required bool                 `yaml:"required"`
+	Content  map[string]MediaType `yaml:"content"`
+}
+
+type MediaType struct {
+	Schema   *Schema            `yaml:"schema"`
+	Example  any                `yaml:"example"`
+	Examples map[string]Example `yaml:"examples"`
+}
+
+type Example struct {
+	Value any `yaml:"value"`
+}
+
+type Schema struct {
+	Ref        string             `yaml:"$ref"`
+	Type       any                `yaml:"type"` // a string, or a list of them since 3.1
+	Format     string             `yaml:"format"`
+	Properties map[string]*Schema `yaml:"properties"`
+	Items      *Schema            `yaml:"items"`
+	Required   []string           `yaml:"required"`
+	Example    any                `yaml:"example"`
+	Examples   []any              `yaml:"examples"`
+	Default    any                `yaml:"default"`
+	Enum       []any              `yaml:"enum"`
+	Const      any                `yaml:"const"`
+	AllOf      []*Schema          `yaml:"allOf"`
+	OneOf      []*Schema          `yaml:"oneOf"`
+	AnyOf      []*Schema          `yaml:"anyOf"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `yaml:"schemas"`
+	Parameters      map[string]*Parameter      `yaml:"parameters"`
+	RequestBodies   map[string]*RequestBody    `yaml:"requestBodies"`
+	SecuritySchemes map[string]*SecurityScheme `yaml:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type   string `yaml:"type"`
+	Scheme string `yaml:"scheme"`
+	Name   string `yaml:"name"`
+	In     string `yaml:"in"`
+}
+
+func Load(r io.Reader) (*Spec, error) {
+	var s Spec
+
+	if err := yaml.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("decoding OpenAPI spec: %w", err)
+	}
+
+	if !strings.HasPrefix(s.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q, only 3.x is supported", s.OpenAPI)
+	}
+
+	return &s, nil
+}
+
+func LoadFile(path string) (*Spec, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening OpenAPI spec: %w", err)
+	}
+	defer f.Close()
+
+	return Load(f)
+}
+
+// refName extracts the component name from a local reference like
+// #/components/schemas/User.
+func refName(ref, kind string) (string, error) {
+	prefix := "#/components/" + kind + "/"
+	if !strings.HasPrefix(ref, prefix) {
+		return "", fmt.Errorf("unsupported reference %s", ref)
+	}
+	name := strings.TrimPrefix(ref, prefix)
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(name), nil
+}
+
+func (s *Spec) parameter(p *Parameter) (*Parameter, error) {
+	return resolve(p, "parameters", "parameter", s.Components.Parameters, func(p *Parameter) string { return p.Ref })
+}
+
+func (s *Spec) requestBody(b *RequestBody) (*RequestBody, error) {
+	return resolve(b, "requestBodies", "request body", s.Components.RequestBodies, func(b *RequestBody) string { return b.Ref })
+}
+
+func (s *Spec) schema(sc *Schema) (*Schema, error) {
+	return resolve(sc, "schemas", "schema", s.Components.Schemas, func(sc *Schema) string { return sc.Ref })
+}
+
+// resolve follows the $ref of v through the components of kind until it
+// reaches one without a $ref. what names the kind in errors.
+func resolve[T any](v *T, kind, what string, components map[string]*T, ref func(*T) string) (*T, error) {
+	seen := make(map[string]bool)
+	for v != nil && ref(v) != "" {
+		r := ref(v)
+		if seen[r] {
+			return nil, fmt.Errorf("circular $ref %s", r)
+		}
+		seen[r] = true
+
+		name, err := refName(r, kind)
+		if err != nil {
+			return nil, err
+		}
+		resolved, ok := components[name]
+		if !ok {
+			return nil, fmt.Errorf("%s %s not found", what, r)
+		}
+		v = resolved
+	}
+	return v, nil
+}
@@ -0,0 +1,387 @@
+package postman
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/bigelle/ghostman/internal/collection"
+	"github.com/bigelle/ghostman/internal/httpcore"
+)
+
+// Postman path variables look like /users/:id
+var pathVarPattern = regexp.MustCompile(`/:([A-Za-z_][A-Za-z0-9_.-]*)`)
+
+type converter struct {
+	warnings []string
+}
+
+func (c *converter) warn(path, format string, args ...any) {
+	if path == "" {
+		path = "collection"
+	}
+	c.warnings = append(c.warnings, path+": "+fmt.Sprintf(format, args...))
+}
+
+// ToCollection converts the whole tree. Everything that can't be carried over,
+// like scripts or saved responses, is reported per item in warnings.
+func (pc Collection) ToCollection() (*collection.Collection, []string) {
+	c := &converter{}
+
+	root := c.folder(pc.Info.Name, "", Item{
+		Name:  pc.Info.Name,
+		Item:  pc.Item,
+		Auth:  pc.Auth,
+		Event: pc.Event,
+	})
+
+	coll := &collection.Collection{Folder: root}
+
+	for _, v := range pc.Variable {
+		if v.Disabled {
+			continue
+		}
+		if coll.Variables == nil {
+			coll.Variables = make(httpcore.Vars)
+		}
+		coll.Variables[v.Key] = v.String()
+	}
+
+	return coll, c.warnings
+}
+
+func (c *converter) folder(name, path string, it Item) collection.Folder {
+	f := collection.Folder{Name: name}
+
+	c.events(path, it.Event)
+	if len(it.Variable) != 0 {
+		c.warn(path, "folder variables are not supported, ignored")
+	}
+
+	if it.Auth != nil {
+		if auth := c.auth(path, it.Auth); auth != nil {
+			f.Defaults = &collection.Defaults{Auth: auth}
+		}
+	}
+
+	folderNames := make(map[string]int)
+	requestNames := make(map[string]int)
+
+	for _, child := range it.Item {
+		if child.IsFolder() {
+			childName := uniqueName(child.Name, folderNames)
+			f.Folders = append(f.Folders, c.folder(childName, join(path, childName), child))
+			continue
+		}
+
+		childName := uniqueName(child.Name, requestNames)
+		f.Requests = append(f.Requests, collection.Request{
+			Name:                childName,
+			RequestSerializable: c.request(join(path, childName), child),
+		})
+	}
+
+	return f
+}
+
+func (c *converter) request(path string, it Item) httpcore.RequestSerializable {
+	r := it.Request
+
+	c.events(path, it.Event)
+	if len(it.Response) != 0 {
+		c.warn(path, "%d saved example responses ignored", len(it.Response))
+	}
+	if r.Proxy != nil {
+		c.warn(path, "proxy settings ignored")
+	}
+	if r.Cert != nil {
+		c.warn(path, "certificate settings ignored")
+	}
+
+	method := strings.ToUpper(r.Method)
+	if method == "" {
+		method = "GET"
+	}
+
+	ser := httpcore.RequestSerializable{
+		Method: method,
+		URL:    c.url(path, r.URL),
+	}
+
+	if query := c.query(r.URL); len(query) != 0 {
+		ser.QueryParams = query
+	}
+
+	for _, h := range r.Header {
+		if h.Disabled {
+			continue
+		}
+		if ser.Headers == nil {
+			ser.Headers = make(map[string][]string)
+		}
+		ser.Headers[h.Key] = append(ser.Headers[h.Key], h.Value)
+	}
+
+	if r.Body != nil && !r.Body.Disabled {
+		ser.Body = c.body(path, r.Body, &ser)
+	}
+
+	if r.Auth != nil {
+		ser.Auth = c.auth(path, r.Auth)
+	}
+
+	return ser
+}
+
+func (c *converter) url(path string, u URL) string {
+	raw, _, _ := strings.Cut(u.Raw, "?")
+
+	vars := make(map[string]string)
+	for _, v := range u.Variable {
+		vars[v.Key] = v.String()
+	}
+
+	return pathVarPattern.ReplaceAllStringFunc(raw, func(m string) string {
+		name := m[2:]
+		if val := vars[name]; val != "" {
+			return "/" + val
+		}
+		return "/{{" + name + "}}"
+	})
+}
+
+func (c *converter) query(u URL) map[string][]string {
+	query := make(map[string][]string)
+
+	if u.Query != nil {
+		for _, q := range u.Query {
+			if !q.Disabled {
+				query[q.Key] = append(query[q.Key], q.Value)
+			}
+		}
+		return query
+	}
+
+	_, rawQuery, ok := strings.Cut(u.Raw, "?")
+	if !ok {
+		return query
+	}
+
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(pair, "=")
+		if uk, err := url.QueryUnescape(k); err == nil {
+			k = uk
+		}
+		if uv, err := url.QueryUnescape(v); err == nil {
+			v = uv
+		}
+		query[k] = append(query[k], v)
+	}
+
+	return query
+}
+
+func (c *converter) body(path string, b *Body, ser *httpcore.RequestSerializable) *httpcore.BodySpec {
+	switch b.Mode {
+	case "raw":
+		text := b.Raw
+		if b.Options != nil && b.Options.Raw != nil {
+			switch b.Options.Raw.Language {
+			case "json":
+				setDefaultHeader(ser, "Content-Type", "application/json")
+			case "xml":
+				setDefaultHeader(ser, "Content-Type", "application/xml")
+			case "html":
+				setDefaultHeader(ser, "Content-Type", "text/html")
+			case "javascript":
+				setDefaultHeader(ser, "Content-Type", "application/javascript")
+			}
+		}
+		return &httpcore.BodySpec{Type: "content", Text: &text}
+
+	case "urlencoded":
+		form := make(map[string][]string)
+		for _, kv := range b.URLEncoded {
+			if !kv.Disabled {
+				form[kv.Key] = append(form[kv.Key], kv.Value)
+			}
+		}
+		setDefaultHeader(ser, "Content-Type", "application/x-www-form-urlencoded")
+		return &httpcore.BodySpec{Type: "form", FormData: &form}
+
+	case "formdata":
+		fields := []httpcore.MultipartField{}
+		for _, kv := range b.FormData {
+			if kv.Disabled {
+				continue
+			}
+			if kv.Type != "file" {
+				fields = append(fields, httpcore.MultipartField{Name: kv.Key, Text: kv.Value})
+				continue
+			}
+
+			src := ""
+			switch s := kv.Src.(type) {
+			case string:
+				src = s
+			case []any:
+				if len(s) > 1 {
+					c.warn(path, "form field %s: only the first of %d files is used", kv.Key, len(s))
+				}
+				if len(s) > 0 {
+					src, _ = s[0].(string)
+				}
+			}
+			if src == "" {
+				c.warn(path, "form field %s: no file selected, skipped", kv.Key)
+				continue
+			}
+			fields = append(fields, httpcore.MultipartField{Name: kv.Key, File: src})
+		}
+		// the body sets a Content-Type with the boundary it was built with
+		dropHeader(ser, "Content-Type")
+		return &httpcore.BodySpec{Type: "multipart", MultipartFields: &fields}
+
+	case "file":
+		if b.File == nil || b.File.Src == "" {
+			c.warn(path, "no file selected for the body, skipped")
+			return nil
+		}
+		src := b.File.Src
+		return &httpcore.BodySpec{Type: "content", File: &src}
+
+	case "graphql":
+		if b.GraphQL == nil {
+			return nil
+		}
+		text, err := graphQLBody(b.GraphQL)
+		if err != nil {
+			c.warn(path, "%v", err)
+		}
+		setDefaultHeader(ser, "Content-Type", "application/json")
+		return &httpcore.BodySpec{Type: "content", Text: &text}
+
+	case "":
+		return nil
+
+	default:
+		c.warn(path, "body mode %s is not supported, ignored", b.Mode)
+		return nil
+	}
+}
+
+func (c *converter) auth(path string, a *Auth) *httpcore.AuthSpec {
+	switch a.Type {
+	case "noauth":
+		return &httpcore.AuthSpec{Type: "none"}
+	case "basic":
+		return &httpcore.AuthSpec{
+			Type:     "basic",
+			Username: a.param(a.Basic, "username"),
+			Password: a.param(a.Basic, "password"),
+		}
+	case "bearer":
+		return &httpcore.AuthSpec{
+			Type:  "bearer",
+			Token: a.param(a.Bearer, "token"),
+		}
+	default:
+		c.warn(path, "auth type %s is not supported, ignored", a.Type)
+		return nil
+	}
+}
+
+func (c *converter) events(path string, events []Event) {
+	for _, e := range events {
+		if e.Disabled {
+			continue
+		}
+		switch e.Listen {
+		case "prerequest":
+			c.warn(path, "pre-request script ignored")
+		case "test":
+			c.warn(path, "test script ignored")
+		default:
+			c.warn(path, "%s script ignored", e.Listen)
+		}
+	}
+}
+
+// ToVars converts an environment export into a ghostman environment file.
+func (e Environment) ToVars() httpcore.Vars {
+	vars := make(httpcore.Vars)
+	for _, v := range e.Values {
+		if v.Enabled != nil && !*v.Enabled {
+			continue
+		}
+		vars[v.Key] = Variable{Key: v.Key, Value: v.Value}.String()
+	}
+	return vars
+}
+
+// graphQLBody builds the JSON body of a GraphQL request. variables that
+// aren't valid JSON are left out and reported.
+func graphQLBody(g *GraphQL) (string, error) {
+	body := struct {
+		Query     string          `json:"query"`
+		Variables json.RawMessage `json:"variables"`
+	}{Query: g.Query, Variables: json.RawMessage("{}")}
+
+	var err error
+	if vars := strings.TrimSpace(g.Variables); vars != "" {
+		if json.Valid([]byte(vars)) {
+			body.Variables = json.RawMessage(vars)
+		} else {
+			err = fmt.Errorf("graphql variables are not valid JSON, ignored")
+		}
+	}
+
+	data, merr := json.Marshal(body)
+	if merr != nil {
+		return "", fmt.Errorf("encoding graphql body: %w", merr)
+	}
+	return string(data), err
+}
+
+// uniqueName makes names usable as collection paths: no slashes and no
+// duplicates among siblings.
+func uniqueName(name string, seen map[string]int) string {
+	name = strings.TrimSpace(strings.ReplaceAll(name, "/", "-"))
+	if name == "" {
+		name = "unnamed"
+	}
+
+	return collection.UniqueName(name, "%s (%d)", seen)
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "/" + name
+}
+
+func setDefaultHeader(ser *httpcore.RequestSerializable, key, val string) {
+	for k := range ser.Headers {
+		if strings.EqualFold(k, key) {
+			return
+		}
+	}
+	if ser.Headers == nil {
+		ser.Headers = make(map[string][]string)
+	}
+	ser.Headers[key] = []string{val}
+}
+
+func dropHeader(ser *httpcore.RequestSerializable, key string) {
+	for k := range ser.Headers {
+		if strings.EqualFold(k, key) {
+			delete(ser.Headers, k)
+		}
+	}
+}
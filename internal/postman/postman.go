@@ -0,0 +1,212 @@
+// Package postman converts Postman Collection v2.1 and environment exports
+// into ghostman collections and environment files.
+// See https://schema.postman.com/collection/json/v2.1.0/draft-07/docs/index.html
+package postman
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type Collection struct {
+	Info     Info       `json:"info"`
+	Item     []Item     `json:"item"`
+	Variable []Variable `json:"variable,omitempty"`
+	Auth     *Auth      `json:"auth,omitempty"`
+	Event    []Event    `json:"event,omitempty"`
+}
+
+type Info struct {
+	Name   string `json:"name"`
+	Schema string `json:"schema"`
+}
+
+// Item is either a folder (Item is set) or a request.
+type Item struct {
+	Name     string     `json:"name"`
+	Item     []Item     `json:"item,omitempty"`
+	Request  *Request   `json:"request,omitempty"`
+	Response []any      `json:"response,omitempty"`
+	Variable []Variable `json:"variable,omitempty"`
+	Auth     *Auth      `json:"auth,omitempty"`
+	Event    []Event    `json:"event,omitempty"`
+}
+
+func (i Item) IsFolder() bool {
+	return i.Request == nil
+}
+
+type Request struct {
+	Method string `json:"method"`
+	URL    URL    `json:"url"`
+	Header []KV   `json:"header,omitempty"`
+	Body   *Body  `json:"body,omitempty"`
+	Auth   *Auth  `json:"auth,omitempty"`
+	Proxy  any    `json:"proxy,omitempty"`
+	Cert   any    `json:"certificate,omitempty"`
+}
+
+// Request may also be just a URL string in exports.
+func (r *Request) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		r.Method = "GET"
+		r.URL.Raw = raw
+		return nil
+	}
+
+	type plain Request
+	return json.Unmarshal(data, (*plain)(r))
+}
+
+// URL may be a plain string or a structured object in exports.
+type URL struct {
+	Raw      string     `json:"raw"`
+	Query    []KV       `json:"query,omitempty"`
+	Variable []Variable `json:"variable,omitempty"`
+}
+
+func (u *URL) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		u.Raw = raw
+		return nil
+	}
+
+	type plain URL
+	return json.Unmarshal(data, (*plain)(u))
+}
+
+type KV struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Src      any    `json:"src,omitempty"`
+}
+
+type Variable struct {
+	Key      string `json:"key"`
+	Value    any    `json:"value"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+func (v Variable) String() string {
+	switch val := v.Value.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	default:
+		b, _ := json.Marshal(val)
+		return string(b)
+	}
+}
+
+type Body struct {
+	Mode       string   `json:"mode"`
+	Raw        string   `json:"raw,omitempty"`
+	URLEncoded []KV     `json:"urlencoded,omitempty"`
+	FormData   []KV     `json:"formdata,omitempty"`
+	File       *File    `json:"file,omitempty"`
+	GraphQL    *GraphQL `json:"graphql,omitempty"`
+	Options    *struct {
+		Raw *struct {
+			Language string `json:"language"`
+		} `json:"raw,omitempty"`
+	} `json:"options,omitempty"`
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+type File struct {
+	Src string `json:"src"`
+}
+
+type GraphQL struct {
+	Query     string `json:"query"`
+	Variables string `json:"variables,omitempty"`
+}
+
+type Auth struct {
+	Type   string     `json:"type"`
+	Basic  []Variable `json:"basic,omitempty"`
+	Bearer []Variable `json:"bearer,omitempty"`
+}
+
+func (a Auth) param(params []Variable, key string) string {
+	for _, p := range params {
+		if p.Key == key {
+			return p.String()
+		}
+	}
+	return ""
+}
+
+type Event struct {
+	Listen   string `json:"listen"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+type Environment struct {
+	Name   string     `json:"name"`
+	Values []EnvValue `json:"values"`
+	Scope  string     `json:"_postman_variable_scope,omitempty"`
+}
+
+type EnvValue struct {
+	Key     string `json:"key"`
+	Value   any    `json:"value"`
+	Enabled *bool  `json:"enabled,omitempty"`
+}
+
+// Load decodes either a collection or an environment export; exactly one of
+// the results is non-nil.
+func Load(r io.Reader) (*Collection, *Environment, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading Postman export: %w", err)
+	}
+
+	var probe struct {
+		Info   *Info           `json:"info"`
+		Values json.RawMessage `json:"values"`
+	}
+	if err = json.Unmarshal(data, &probe); err != nil {
+		return nil, nil, fmt.Errorf("decoding Postman export: %w", err)
+	}
+
+	switch {
+	case probe.Info != nil:
+		if probe.Info.Schema != "" && !strings.Contains(probe.Info.Schema, "v2.1") {
+			return nil, nil, fmt.Errorf("unsupported collection schema %s, only v2.1 is supported", probe.Info.Schema)
+		}
+		var c Collection
+		if err = json.Unmarshal(data, &c); err != nil {
+			return nil, nil, fmt.Errorf("decoding Postman collection: %w", err)
+		}
+		return &c, nil, nil
+
+	case probe.Values != nil:
+		var e Environment
+		if err = json.Unmarshal(data, &e); err != nil {
+			return nil, nil, fmt.Errorf("decoding Postman environment: %w", err)
+		}
+		return nil, &e, nil
+
+	default:
+		return nil, nil, fmt.Errorf("neither a Postman collection nor an environment")
+	}
+}
+
+func LoadFile(path string) (*Collection, *Environment, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening Postman export: %w", err)
+	}
+	defer f.Close()
+
+	return Load(f)
+}
@@ -0,0 +1,171 @@
+package postman
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bigelle/ghostman/internal/httpcore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCollection = `{
+  "info": {
+    "name": "Users API",
+    "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+  },
+  "auth": {"type": "bearer", "bearer": [{"key": "token", "value": "{{token}}", "type": "string"}]},
+  "variable": [{"key": "baseUrl", "value": "https://api.example.com"}, {"key": "limit", "value": 10}],
+  "item": [
+    {
+      "name": "users",
+      "item": [
+        {
+          "name": "Get user",
+          "event": [{"listen": "test", "script": {"exec": ["pm.test()"]}}],
+          "request": {
+            "method": "GET",
+            "header": [
+              {"key": "Accept", "value": "application/json"},
+              {"key": "X-Debug", "value": "1", "disabled": true}
+            ],
+            "url": {
+              "raw": "{{baseUrl}}/users/:id?expand=roles",
+              "query": [{"key": "expand", "value": "roles"}, {"key": "skip", "value": "1", "disabled": true}],
+              "variable": [{"key": "id", "value": ""}]
+            }
+          }
+        },
+        {
+          "name": "Create user",
+          "request": {
+            "method": "POST",
+            "auth": {"type": "basic", "basic": [
+              {"key": "username", "value": "admin"}, {"key": "password", "value": "{{password}}"}
+            ]},
+            "body": {"mode": "raw", "raw": "{\"name\": \"ghost\"}", "options": {"raw": {"language": "json"}}},
+            "url": "{{baseUrl}}/users"
+          }
+        }
+      ]
+    },
+    {
+      "name": "Login",
+      "request": {
+        "method": "POST",
+        "auth": {"type": "oauth2"},
+        "body": {"mode": "urlencoded", "urlencoded": [{"key": "user", "value": "ghost"}]},
+        "url": "{{baseUrl}}/login"
+      }
+    }
+  ]
+}`
+
+func TestCollection_ToCollection(t *testing.T) {
+	pc, env, err := Load(strings.NewReader(testCollection))
+	require.NoError(t, err)
+	require.Nil(t, env)
+
+	c, warnings := pc.ToCollection()
+	assert.Equal(t, []string{
+		"users/Get user: test script ignored",
+		"Login: auth type oauth2 is not supported, ignored",
+	}, warnings)
+
+	assert.Equal(t, httpcore.Vars{"baseUrl": "https://api.example.com", "limit": "10"}, c.Variables)
+	assert.Equal(t, []string{"Login", "users/Get user", "users/Create user"}, c.Paths())
+
+	get, err := c.Resolve("users/Get user")
+	require.NoError(t, err)
+	assert.Equal(t, httpcore.RequestSerializable{
+		Method:      "GET",
+		URL:         "{{baseUrl}}/users/{{id}}",
+		QueryParams: map[string][]string{"expand": {"roles"}},
+		Headers:     map[string][]string{"Accept": {"application/json"}},
+		Auth:        &httpcore.AuthSpec{Type: "bearer", Token: "{{token}}"},
+	}, get)
+
+	create, err := c.Resolve("users/Create user")
+	require.NoError(t, err)
+	assert.Equal(t, &httpcore.AuthSpec{Type: "basic", Username: "admin", Password: "{{password}}"}, create.Auth)
+	assert.Equal(t, []string{"application/json"}, create.Headers["Content-Type"])
+	assert.Equal(t, `{"name": "ghost"}`, *create.Body.Text)
+
+	login, err := c.Resolve("Login")
+	require.NoError(t, err)
+	assert.Equal(t, "form", login.Body.Type)
+	// unsupported auth on the request falls back to the collection one
+	assert.Equal(t, &httpcore.AuthSpec{Type: "bearer", Token: "{{token}}"}, login.Auth)
+}
+
+func TestEnvironment_ToVars(t *testing.T) {
+	_, env, err := Load(strings.NewReader(`{
+		"name": "staging",
+		"values": [
+			{"key": "baseUrl", "value": "https://staging.example.com", "enabled": true},
+			{"key": "old", "value": "x", "enabled": false}
+		],
+		"_postman_variable_scope": "environment"
+	}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, httpcore.Vars{"baseUrl": "https://staging.example.com"}, env.ToVars())
+}
+
+func TestCollection_FormData(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, "user=%s", r.FormValue("user"))
+	}))
+	defer srv.Close()
+
+	pc, _, err := Load(strings.NewReader(`{
+		"info": {"name": "Upload", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+		"item": [{
+			"name": "Upload",
+			"request": {
+				"method": "POST",
+				"header": [{"key": "Content-Type", "value": "multipart/form-data"}],
+				"body": {"mode": "formdata", "formdata": [{"key": "user", "value": "ghost", "type": "text"}]},
+				"url": "` + srv.URL + `"
+			}
+		}]
+	}`))
+	require.NoError(t, err)
+
+	c, _ := pc.ToCollection()
+	ser, err := c.Resolve("Upload")
+	require.NoError(t, err)
+
+	req, err := httpcore.NewRequestFromSerializable(ser)
+	require.NoError(t, err)
+	resp, err := httpcore.NewClient().Send(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "user=ghost", string(resp.Body()))
+}
+
+func TestGraphQLBody(t *testing.T) {
+	body, err := graphQLBody(&GraphQL{Query: "query {\x01 user(id: \"1\") }", Variables: ` {"id": 1} `})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"query": "query {\u0001 user(id: \"1\") }", "variables": {"id": 1}}`, body)
+
+	body, err = graphQLBody(&GraphQL{Query: "{ me }", Variables: "{id: 1"})
+	assert.ErrorContains(t, err, "graphql variables are not valid JSON")
+	assert.JSONEq(t, `{"query": "{ me }", "variables": {}}`, body)
+}
+
+func TestUniqueName(t *testing.T) {
+	seen := make(map[string]int)
+	var names []string
+	for _, n := range []string{"a", "a", "a (2)", "a", "b/c", ""} {
+		names = append(names, uniqueName(n, seen))
+	}
+	assert.Equal(t, []string{"a", "a (2)", "a (2) (2)", "a (3)", "b-c", "unnamed"}, names)
+}